@@ -2,6 +2,7 @@ package irc
 
 import (
 	"strings"
+	"sync"
 
 	"github.com/gissleh/irc/list"
 )
@@ -12,6 +13,9 @@ type Channel struct {
 	name     string
 	userlist *list.List
 	parted   bool
+
+	mutex sync.RWMutex
+	key   string
 }
 
 // ID returns a unique ID for the channel target.
@@ -33,6 +37,7 @@ func (channel *Channel) State() ClientStateTarget {
 	return ClientStateTarget{
 		Kind:  "channel",
 		Name:  channel.name,
+		Key:   channel.Key(),
 		Users: channel.userlist.Users(),
 	}
 }
@@ -42,6 +47,15 @@ func (channel *Channel) UserList() list.Immutable {
 	return channel.userlist.Immutable()
 }
 
+// Key gets the channel key, which is used when rejoining. It's empty if the channel has no key,
+// or the client does not know it.
+func (channel *Channel) Key() string {
+	channel.mutex.RLock()
+	defer channel.mutex.RUnlock()
+
+	return channel.key
+}
+
 // Parted returnes whether the channel has been parted
 func (channel *Channel) Parted() bool {
 	return channel.parted
@@ -116,35 +130,14 @@ func (channel *Channel) Handle(event *Event, client *Client) {
 		}
 	case "packet.mode":
 		{
-			isupport := client.ISupport()
-			plus := false
-			argIndex := 2
-
-			for _, ch := range event.Arg(1) {
-				if ch == '+' {
-					plus = true
-					continue
-				}
-				if ch == '-' {
-					plus = false
-					continue
-				}
-
-				arg := ""
-				if isupport.ModeTakesArgument(ch, plus) {
-					arg = event.Arg(argIndex)
-					argIndex++
-				}
-
-				if isupport.IsPermissionMode(ch) {
-					if plus {
-						channel.userlist.AddMode(arg, ch)
-					} else {
-						channel.userlist.RemoveMode(arg, ch)
-					}
-				} else {
-					// TODO: track non-permission modes
-				}
+			if len(event.Args) > 1 {
+				channel.handleModes(client, event.Arg(1), event.Args[2:])
+			}
+		}
+	case "packet.324": // Channel modes
+		{
+			if len(event.Args) > 2 {
+				channel.handleModes(client, event.Arg(2), event.Args[3:])
 			}
 		}
 	case "packet.privmsg", "ctcp.action":
@@ -155,3 +148,52 @@ func (channel *Channel) Handle(event *Event, client *Client) {
 		}
 	}
 }
+
+// handleModes applies the mode changes in a MODE or 324 to the channel.
+func (channel *Channel) handleModes(client *Client, modes string, args []string) {
+	isupport := client.ISupport()
+	plus := false
+	argIndex := 0
+
+	for _, ch := range modes {
+		if ch == '+' {
+			plus = true
+			continue
+		}
+		if ch == '-' {
+			plus = false
+			continue
+		}
+
+		arg := ""
+		if isupport.ModeTakesArgument(ch, plus) {
+			if argIndex < len(args) {
+				arg = args[argIndex]
+			}
+			argIndex++
+		}
+
+		if isupport.IsPermissionMode(ch) {
+			if plus {
+				channel.userlist.AddMode(arg, ch)
+			} else {
+				channel.userlist.RemoveMode(arg, ch)
+			}
+		} else if ch == 'k' {
+			// Some servers hide the key from users who are not allowed to see it.
+			if !plus {
+				channel.setKey("")
+			} else if arg != "" && arg != "*" {
+				channel.setKey(arg)
+			}
+		} else {
+			// TODO: track non-permission modes
+		}
+	}
+}
+
+func (channel *Channel) setKey(key string) {
+	channel.mutex.Lock()
+	channel.key = key
+	channel.mutex.Unlock()
+}
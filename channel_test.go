@@ -0,0 +1,79 @@
+package irc_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/internal/irctest"
+)
+
+func TestChannel_Key(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+
+	checkKeys := func(keys map[string]string) error {
+		for name, key := range keys {
+			channel := client.Channel(name)
+			if channel == nil {
+				return fmt.Errorf("%s should be joined", name)
+			}
+			if channel.Key() != key {
+				return fmt.Errorf("%s should have key %q, not %q", name, key, channel.Key())
+			}
+		}
+
+		return nil
+	}
+
+	interaction := irctest.Interaction{
+		Strict: true,
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Callback: func() error {
+				client.JoinWithKey("#Keyed", "secret")
+				<-client.EmitInput("/join #Input,#Open inputkey", nil).Done()
+				return nil
+			}},
+			irctest.InteractionLine{Client: "JOIN #Keyed secret"},
+			irctest.InteractionLine{Client: "JOIN #Input,#Open inputkey"},
+			irctest.InteractionLine{Server: ":Test!~Tester@127.0.0.1 JOIN #Keyed"},
+			irctest.InteractionLine{Server: ":Test!~Tester@127.0.0.1 JOIN #Input"},
+			irctest.InteractionLine{Server: ":Test!~Tester@127.0.0.1 JOIN #Open"},
+			irctest.InteractionLine{Server: ":Test!~Tester@127.0.0.1 JOIN #Moded"},
+			irctest.InteractionLine{Server: ":testserver.example.com 324 Test #Open +nt"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 MODE #Moded +ok Test modekey"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 MODE #Input -k inputkey"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+			irctest.InteractionLine{Callback: func() error {
+				return checkKeys(map[string]string{"#Keyed": "secret", "#Input": "", "#Open": "", "#Moded": "modekey"})
+			}},
+			irctest.InteractionLine{Server: ":testserver.example.com 324 Test #Open +knt openkey"},
+			irctest.InteractionLine{Server: ":testserver.example.com 376 Test :End of /MOTD command."},
+			irctest.InteractionLine{Client: "JOIN #Keyed,#Open,#Moded,#Input secret,openkey,modekey"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+			irctest.InteractionLine{Callback: func() error {
+				return checkKeys(map[string]string{"#Open": "openkey"})
+			}},
+			irctest.InteractionLine{Callback: func() error {
+				client.JoinWithKey("#Wrong", "wrongkey")
+				return nil
+			}},
+			irctest.InteractionLine{Client: "JOIN #Wrong wrongkey"},
+			irctest.InteractionLine{Server: ":testserver.example.com 475 Test #Wrong :Cannot join channel (+k) - bad key"},
+			irctest.InteractionLine{Server: ":Test!~Tester@127.0.0.1 JOIN #Wrong"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+			irctest.InteractionLine{Callback: func() error {
+				return checkKeys(map[string]string{"#Wrong": ""})
+			}},
+		),
+	}
+	runInteraction(t, client, &interaction)
+}
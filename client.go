@@ -38,6 +38,11 @@ var supportedCaps = []string{
 	"echo-message",
 	"draft/languages",
 	"sasl",
+	"batch",
+	"soju.im/bouncer-networks",
+	"soju.im/bouncer-networks-notify",
+	"znc.in/playback",
+	"znc.in/self-message",
 }
 
 // ErrNoConnection is returned if you try to do something requiring a connection,
@@ -73,32 +78,72 @@ type Client struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
+	connectAddr string
+	connectSSL  bool
+
 	events chan *Event
 	sends  chan string
 
-	lastSend time.Time
+	lastReceive time.Time
+	pingToken   string
+	pingSent    time.Time
+	lag         time.Duration
 
 	capEnabled    map[string]bool
 	capData       map[string]string
 	capsRequested []string
+	extraCaps     []string
 
 	nick     string
 	user     string
 	host     string
+	modes    string
+	account  string
+	away     bool
 	quit     bool
 	ready    bool
 	isupport isupport.ISupport
 	values   map[string]interface{}
 
-	status  *Status
-	targets []Target
+	status   *Status
+	targets  []Target
+	batches  map[string]*clientBatch
+	joinKeys map[string]string
+	netsplit netsplitState
+
+	pendingJoins  map[string]bool
+	whoisRequests map[string]*whoisRequest
+	listRequest   *listRequest
+	listSkip      int
+	listLock      chan struct{}
+
+	bouncerNetworks map[string]map[string]string
+	bouncerRequests []*bouncerRequest
+
+	lastSeen       map[string]time.Time
+	playbackStart  time.Time
+	disconnectTime time.Time
 
-	handlers []Handler
+	router *router
+}
+
+// clientBatch is an open IRCv3 batch.
+type clientBatch struct {
+	Type   string
+	Params []string
 }
 
 // New creates a new client. The context can be context.Background if you want manually to
 // tear down clients upon quitting.
 func New(ctx context.Context, config Config) *Client {
+	client := newClient(ctx, config)
+	client.start()
+
+	return client
+}
+
+// newClient sets up a client without starting it, so that NewFromState can restore state into it first.
+func newClient(ctx context.Context, config Config) *Client {
 	client := &Client{
 		id:         generateClientID("C"),
 		values:     make(map[string]interface{}),
@@ -108,18 +153,31 @@ func New(ctx context.Context, config Config) *Client {
 		capData:    make(map[string]string),
 		config:     config.WithDefaults(),
 		status:     &Status{id: generateClientID("T")},
+		router:     newRouter(),
+		batches:    make(map[string]*clientBatch),
+		joinKeys:   make(map[string]string),
+		netsplit:   netsplitState{nicks: make(map[string]netsplitNick)},
+
+		pendingJoins:  make(map[string]bool),
+		whoisRequests: make(map[string]*whoisRequest),
+		listLock:      make(chan struct{}, 1),
+
+		bouncerNetworks: make(map[string]map[string]string),
+		lastSeen:        make(map[string]time.Time),
 	}
 
 	client.ctx, client.cancel = context.WithCancel(ctx)
 
+	return client
+}
+
+func (client *Client) start() {
 	_ = client.AddTarget(client.status)
 
 	go client.handleEventLoop()
 	go client.handleSendLoop()
 
 	client.EmitNonBlocking(NewEvent("client", "create"))
-
-	return client
 }
 
 // Context gets the client's context. It's cancelled if the parent context used
@@ -203,9 +261,13 @@ func (client *Client) State() ClientState {
 	client.mutex.RLock()
 
 	state := ClientState{
+		ID:        client.id,
 		Nick:      client.nick,
 		User:      client.user,
 		Host:      client.host,
+		Modes:     client.modes,
+		Account:   client.account,
+		Away:      client.away,
 		Connected: client.conn != nil,
 		Ready:     client.ready,
 		Quit:      client.quit,
@@ -233,7 +295,8 @@ func (client *Client) State() ClientState {
 	return state
 }
 
-// Connect connects to the server by addr.
+// Connect connects to the server by addr. If addr is a `ws://` or `wss://` URL, it connects over
+// WebSocket instead, in which case ssl is decided by the scheme.
 func (client *Client) Connect(addr string, ssl bool) (err error) {
 	var conn net.Conn
 
@@ -245,11 +308,21 @@ func (client *Client) Connect(addr string, ssl bool) (err error) {
 
 	client.mutex.Lock()
 	client.quit = false
+	client.connectAddr = addr
+	client.connectSSL = ssl
 	client.mutex.Unlock()
 
 	client.EmitNonBlocking(NewEvent("client", "connecting"))
 
-	if ssl {
+	if isWebSocketAddr(addr) {
+		conn, err = dialWebSocket(addr, client.config.SkipSSLVerification)
+		if err != nil {
+			if !client.Destroyed() {
+				client.EmitNonBlocking(NewErrorEvent("connect", "WebSocket connect failed: "+err.Error(), "connect_failed_websocket", err))
+			}
+			return err
+		}
+	} else if ssl {
 		conn, err = tls.Dial("tcp", addr, &tls.Config{
 			InsecureSkipVerify: client.config.SkipSSLVerification,
 		})
@@ -288,6 +361,10 @@ func (client *Client) Connect(addr string, ssl bool) (err error) {
 			}
 			line = replacer.Replace(line)
 
+			client.mutex.Lock()
+			client.lastReceive = time.Now()
+			client.mutex.Unlock()
+
 			event, err := ParsePacket(line)
 			if err != nil {
 				client.mutex.RLock()
@@ -315,6 +392,10 @@ func (client *Client) Connect(addr string, ssl bool) (err error) {
 
 	client.mutex.Lock()
 	client.conn = conn
+	client.lastReceive = time.Now()
+	client.pingToken = ""
+	client.pingSent = time.Now()
+	client.lag = 0
 	client.mutex.Unlock()
 
 	return nil
@@ -431,6 +512,101 @@ func (client *Client) Sayf(targetName string, format string, a ...interface{}) {
 	client.Say(targetName, fmt.Sprintf(format, a...))
 }
 
+// SayMany sends a PRIVMSG with the text to all the targets, with as many targets per line as
+// the server's TARGMAX allows. It sends one line per target if the server does not advertise it.
+func (client *Client) SayMany(targetNames []string, text string) {
+	client.sendMany("PRIVMSG", targetNames, text)
+}
+
+// NoticeMany is SayMany with a NOTICE.
+func (client *Client) NoticeMany(targetNames []string, text string) {
+	client.sendMany("NOTICE", targetNames, text)
+}
+
+func (client *Client) sendMany(verb string, targetNames []string, text string) {
+	groups := client.targetGroups(verb, targetNames)
+	if len(groups) == 0 {
+		return
+	}
+
+	// The text is cut once, so it must fit with the longest group.
+	longest := ""
+	for _, group := range groups {
+		if len(group) > len(longest) {
+			longest = group
+		}
+	}
+	overhead := client.PrivmsgOverhead(longest, false)
+	cuts := ircutil.CutMessage(text, overhead)
+
+	for _, group := range groups {
+		for _, cut := range cuts {
+			client.SendQueuedf("%s %s :%s", verb, group, cut)
+		}
+	}
+}
+
+// maxTargetGroupLength is the longest a comma-separated list of targets can be, to leave room
+// for the message even when TARGMAX has no limit.
+const maxTargetGroupLength = 200
+
+// targetGroups joins the target names into comma-separated groups that fit within the TARGMAX for
+// the command.
+func (client *Client) targetGroups(command string, targetNames []string) []string {
+	maxTargets, ok := client.isupport.TargMax(command)
+	if !ok {
+		maxTargets = 1
+	}
+
+	groups := make([]string, 0, len(targetNames))
+	current := make([]string, 0, len(targetNames))
+	length := 0
+	for _, targetName := range targetNames {
+		if len(current) > 0 && ((maxTargets > 0 && len(current) >= maxTargets) || length+1+len(targetName) > maxTargetGroupLength) {
+			groups = append(groups, strings.Join(current, ","))
+			current = current[:0]
+			length = 0
+		}
+
+		if len(current) > 0 {
+			length++
+		}
+		length += len(targetName)
+		current = append(current, targetName)
+	}
+	if len(current) > 0 {
+		groups = append(groups, strings.Join(current, ","))
+	}
+
+	return groups
+}
+
+// SayStatus sends a PRIVMSG to the users in the channel with the status prefix (like `@` for
+// operators) or higher, cutting the message if it gets too long. The prefix must be in the
+// server's STATUSMSG.
+func (client *Client) SayStatus(prefix, channelName, text string) {
+	client.Say(prefix+channelName, text)
+}
+
+// splitStatusMsg splits a target like `@#Channel` into the STATUSMSG prefix and the channel name.
+// The prefix is empty if there is none.
+func (client *Client) splitStatusMsg(targetName string) (prefix, channelName string) {
+	statusMsg := client.isupport.StatusMsg()
+	if statusMsg == "" {
+		return "", targetName
+	}
+
+	i := 0
+	for i < len(targetName) && strings.IndexByte(statusMsg, targetName[i]) != -1 {
+		i++
+	}
+	if i == 0 || !client.isupport.IsChannel(targetName[i:]) {
+		return "", targetName
+	}
+
+	return targetName[:i], targetName[i:]
+}
+
 // Describe sends a CTCP ACTION with the target name and text, cutting the message if it gets too long.
 func (client *Client) Describe(targetName string, text string) {
 	overhead := client.PrivmsgOverhead(targetName, true)
@@ -581,9 +757,65 @@ func (client *Client) PrivmsgOverhead(targetName string, action bool) int {
 	return ircutil.MessageOverhead(client.nick, client.user, client.host, targetName, action)
 }
 
-// Join joins one or more channels without a key.
+// Join joins one or more channels without a key. The channels are split across as many JOIN
+// lines as needed, and channels that would go over the server's CHANLIMIT are skipped with an
+// error event.
 func (client *Client) Join(channels ...string) {
-	client.SendQueuedf("JOIN %s", strings.Join(channels, ","))
+	requests := make([]joinRequest, 0, len(channels))
+	for _, channel := range channels {
+		requests = append(requests, joinRequest{name: channel})
+	}
+
+	client.sendJoins(requests, client.joinedChannelNames())
+}
+
+// JoinWithKey joins a channel with a key. The key is stored on the channel once it's joined, and
+// used when rejoining it.
+func (client *Client) JoinWithKey(channel, key string) {
+	client.rememberJoinKeys([]string{channel}, []string{key})
+	client.sendJoins([]joinRequest{{name: channel, key: key}}, client.joinedChannelNames())
+}
+
+// joinedChannelNames gets the names of the channels that have not been parted.
+func (client *Client) joinedChannelNames() []string {
+	channels := client.Channels()
+	names := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		if !channel.parted {
+			names = append(names, channel.name)
+		}
+	}
+
+	return names
+}
+
+// rememberJoinKeys stores the keys for the channels until the server confirms or rejects the JOIN.
+// The keys are matched with the channels by position.
+func (client *Client) rememberJoinKeys(channels, keys []string) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	for i, key := range keys {
+		if i >= len(channels) {
+			break
+		}
+		if key != "" {
+			client.joinKeys[strings.ToLower(channels[i])] = key
+		}
+	}
+}
+
+// takeJoinKey gets and forgets the stored key for the channel.
+func (client *Client) takeJoinKey(channel string) (string, bool) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	key, ok := client.joinKeys[strings.ToLower(channel)]
+	if ok {
+		delete(client.joinKeys, strings.ToLower(channel))
+	}
+
+	return key, ok
 }
 
 // Part parts one or more channels.
@@ -736,6 +968,7 @@ func (client *Client) RemoveTarget(target Target) (id string, err error) {
 
 			client.targets[i] = client.targets[len(client.targets)-1]
 			client.targets = client.targets[:len(client.targets)-1]
+			delete(client.lastSeen, strings.ToLower(target.Name()))
 
 			// Ensure the channel has been parted
 			if channel, ok := target.(*Channel); ok && !channel.parted {
@@ -772,16 +1005,27 @@ func (client *Client) FindUser(nick string) (u list.User, ok bool) {
 	return list.User{}, false
 }
 
-// AddHandler adds a handler. This is thread safe, unlike adding global handlers.
-func (client *Client) AddHandler(handler Handler) {
-	client.mutex.Lock()
-	client.handlers = append(client.handlers[:0], client.handlers...)
-	client.handlers = append(client.handlers, handler)
-	client.mutex.Unlock()
+// AddHandler adds a handler for all events. This is thread safe, unlike adding global handlers.
+func (client *Client) AddHandler(handler Handler) HandlerHandle {
+	return client.router.add("*", 0, handler)
+}
+
+// On adds a handler for events matching the pattern, which is either an exact event
+// name (`packet.privmsg`), all events of a kind (`ctcp.*`) or all events (`*`).
+func (client *Client) On(pattern string, handler Handler) HandlerHandle {
+	return client.router.add(pattern, 0, handler)
+}
+
+// OnPriority is like On, but handlers with a higher priority run before the ones with
+// a lower one. Handlers with the same priority run in the order they were added, and
+// the default priority is 0.
+func (client *Client) OnPriority(pattern string, priority int, handler Handler) HandlerHandle {
+	return client.router.add(pattern, priority, handler)
 }
 
 func (client *Client) handleEventLoop() {
 	ticker := time.NewTicker(time.Second * 30)
+	keepaliveTicker := time.NewTicker(client.keepaliveInterval())
 
 	for {
 		select {
@@ -794,7 +1038,7 @@ func (client *Client) handleEventLoop() {
 				client.handleEvent(event)
 
 				// Turn an unhandled input into a raw command.
-				if event.kind == "input" && !event.preventedDefault {
+				if event.kind == "input" && !event.preventedDefault && !event.replayed {
 					client.SendQueued(strings.ToUpper(event.verb) + " " + event.Text)
 				}
 
@@ -809,6 +1053,10 @@ func (client *Client) handleEventLoop() {
 
 				event.cancel()
 			}
+		case <-keepaliveTicker.C:
+			{
+				client.handleKeepalive()
+			}
 		case <-client.ctx.Done():
 			{
 				goto end
@@ -819,6 +1067,7 @@ func (client *Client) handleEventLoop() {
 end:
 
 	ticker.Stop()
+	keepaliveTicker.Stop()
 
 	_ = client.Disconnect(false)
 }
@@ -850,17 +1099,26 @@ func (client *Client) handleSendLoop() {
 
 // handleEvent is always first and gets to break a few rules.
 func (client *Client) handleEvent(event *Event) {
+	// Replayed events have already happened, so they are only for the handlers.
+	if event.replayed {
+		client.runHandlers(event)
+		return
+	}
+
 	sentCapEnd := false
 
+	serverTime := time.Time{}
+	if timeTag, ok := event.Tags["time"]; ok {
+		parsedTime, err := time.Parse(time.RFC3339Nano, timeTag)
+		if err == nil && parsedTime.Year() > 2000 {
+			serverTime = parsedTime
+		}
+	}
+
 	// Only use IRCv3 `server-time` to overwrite when requested. Frontends/dependents can still
 	// get this information.
-	if client.config.UseServerTime {
-		if timeTag, ok := event.Tags["time"]; ok {
-			serverTime, err := time.Parse(time.RFC3339Nano, timeTag)
-			if err == nil && serverTime.Year() > 2000 {
-				event.Time = serverTime
-			}
-		}
+	if client.config.UseServerTime && !serverTime.IsZero() {
+		event.Time = serverTime
 	}
 
 	// For events that were created with targets, handle them now there now.
@@ -871,15 +1129,14 @@ func (client *Client) handleEvent(event *Event) {
 	switch event.name {
 
 	// Ping Pong
-	case "hook.tick":
+	case "packet.pong":
 		{
-			client.mutex.RLock()
-			lastSend := time.Since(client.lastSend)
-			client.mutex.RUnlock()
-
-			if lastSend > time.Second*120 {
-				_ = client.Sendf("PING :%x%x%x", mathRand.Int63(), mathRand.Int63(), mathRand.Int63())
+			token := event.Text
+			if token == "" {
+				token = event.Arg(len(event.Args) - 1)
 			}
+
+			client.handlePong(token)
 		}
 	case "packet.ping":
 		{
@@ -894,6 +1151,30 @@ func (client *Client) handleEvent(event *Event) {
 			_ = client.Send(message)
 		}
 
+	case "client.disconnect":
+		{
+			client.mutex.Lock()
+			client.disconnectTime = time.Now()
+			for nick := range client.whoisRequests {
+				client.finishWhois(nick, ErrNoConnection)
+			}
+			if client.listRequest != nil {
+				client.listRequest.err = ErrNoConnection
+				close(client.listRequest.done)
+				client.listRequest = nil
+			}
+			client.listSkip = 0
+			for _, request := range client.bouncerRequests {
+				request.err = ErrNoConnection
+				close(request.done)
+			}
+			client.bouncerRequests = client.bouncerRequests[:0]
+			for ref := range client.batches {
+				delete(client.batches, ref)
+			}
+			client.mutex.Unlock()
+		}
+
 	// Client Registration
 	case "client.connect":
 		{
@@ -923,7 +1204,14 @@ func (client *Client) handleEvent(event *Event) {
 			client.nick = ""
 			client.user = ""
 			client.host = ""
+			client.modes = ""
+			client.account = ""
+			client.away = false
 			client.capsRequested = client.capsRequested[:0]
+			client.values["internal.saslPending"] = false
+			for key := range client.pendingJoins {
+				delete(client.pendingJoins, key)
+			}
 			for key := range client.capData {
 				delete(client.capData, key)
 			}
@@ -988,8 +1276,8 @@ func (client *Client) handleEvent(event *Event) {
 		{
 			client.handleInTargets(event.Nick, event)
 
-			if event.Nick == client.nick {
-				client.SetValue("nick", event.Arg(0))
+			if event.Nick == client.Nick() {
+				client.setNick(event.Arg(0))
 			}
 		}
 
@@ -1029,14 +1317,10 @@ func (client *Client) handleEvent(event *Event) {
 							client.capData[key] = split[1]
 						}
 
-						for i := range supportedCaps {
-							if supportedCaps[i] == key {
-								client.mutex.Lock()
-								client.capsRequested = append(client.capsRequested, key)
-								client.mutex.Unlock()
-
-								break
-							}
+						if client.capWanted(key) {
+							client.mutex.Lock()
+							client.capsRequested = append(client.capsRequested, key)
+							client.mutex.Unlock()
 						}
 					}
 
@@ -1053,7 +1337,7 @@ func (client *Client) handleEvent(event *Event) {
 							_ = client.Send("CAP REQ :" + requestedCaps)
 						} else {
 							sentCapEnd = true
-							_ = client.Send("CAP END")
+							client.endCapNegotiation()
 						}
 					}
 				}
@@ -1089,6 +1373,9 @@ func (client *Client) handleEvent(event *Event) {
 								if selectedMechanism != "" {
 									_ = client.Sendf("AUTHENTICATE %s", selectedMechanism)
 									client.SetValue("sasl.usingMethod", "PLAIN")
+
+									// Registration must wait until the authentication is done.
+									client.SetValue("internal.saslPending", true)
 								}
 							}
 
@@ -1132,9 +1419,10 @@ func (client *Client) handleEvent(event *Event) {
 						}
 					}
 
-					if !client.Ready() {
+					saslPending, _ := client.Value("internal.saslPending").(bool)
+					if !client.Ready() && !saslPending {
 						sentCapEnd = true
-						_ = client.Send("CAP END")
+						client.endCapNegotiation()
 					}
 				}
 			case "NAK":
@@ -1162,10 +1450,8 @@ func (client *Client) handleEvent(event *Event) {
 					requests := make([]string, 0, len(capTokens))
 
 					for _, token := range capTokens {
-						for i := range supportedCaps {
-							if supportedCaps[i] == token {
-								requests = append(requests, token)
-							}
+						if client.capWanted(token) {
+							requests = append(requests, token)
 						}
 					}
 
@@ -1212,20 +1498,82 @@ func (client *Client) handleEvent(event *Event) {
 				}
 			}
 		}
-	case "packet.904": // Auth failed
+	case "packet.902", "packet.904", "packet.905": // Auth failed
 		{
 			// Cancel authentication.
 			_ = client.Sendf("AUTHENTICATE *")
 			client.SetValue("sasl.usingMethod", (interface{})(nil))
+
+			// Registration should continue without it.
+			if saslPending, _ := client.Value("internal.saslPending").(bool); saslPending {
+				client.SetValue("internal.saslPending", false)
+				sentCapEnd = true
+				client.endCapNegotiation()
+			}
 		}
-	case "packet.903", "packet.906": // Auth ended
+	case "packet.903", "packet.906", "packet.907": // Auth ended
 		{
+			if saslPending, _ := client.Value("internal.saslPending").(bool); saslPending {
+				client.SetValue("internal.saslPending", false)
+				sentCapEnd = true
+				client.endCapNegotiation()
+			}
+
 			// A bit dirty, but it'll get the nick rotation started again.
 			if client.Nick() == "" {
 				_ = client.Sendf("NICK %s", client.config.Nick)
 			}
 		}
 
+	// Batches
+	case "packet.batch":
+		{
+			ref := event.Arg(0)
+			if len(ref) < 2 {
+				break
+			}
+
+			if ref[0] == '+' {
+				client.mutex.Lock()
+				client.batches[ref[1:]] = &clientBatch{Type: event.Arg(1), Params: append(event.Args[:0:0], event.Args[2:]...)}
+				client.mutex.Unlock()
+
+				if event.Arg(1) == "soju.im/bouncer-networks" {
+					// It's the full list, so clear out the ones that aren't part of it.
+					client.mutex.Lock()
+					for id := range client.bouncerNetworks {
+						delete(client.bouncerNetworks, id)
+					}
+					client.mutex.Unlock()
+				}
+			} else if ref[0] == '-' {
+				client.mutex.Lock()
+				batch := client.batches[ref[1:]]
+				delete(client.batches, ref[1:])
+
+				if batch != nil && batch.Type == "soju.im/bouncer-networks" {
+					client.finishBouncerRequest("LISTNETWORKS", "", "", nil)
+				}
+				client.mutex.Unlock()
+
+				if batch != nil && (batch.Type == "netsplit" || batch.Type == "netjoin") {
+					client.flushNetsplits()
+				}
+			}
+		}
+
+	// soju.im/bouncer-networks
+	case "packet.bouncer":
+		{
+			client.handleBouncer(event)
+		}
+	case "packet.fail":
+		{
+			if event.Arg(0) == "BOUNCER" {
+				client.handleBouncer(event)
+			}
+		}
+
 	// User/host detection
 	case "packet.352": // WHO reply
 		{
@@ -1234,41 +1582,59 @@ func (client *Client) handleEvent(event *Event) {
 			user := event.Args[2]
 			host := event.Args[3]
 
-			if nick == client.nick {
-				client.mutex.Lock()
-				client.user = user
-				client.host = host
-				client.mutex.Unlock()
+			if nick == client.Nick() {
+				client.setUserHost(user, host)
 			}
 		}
 
 	case "packet.chghost":
 		{
-			if event.Nick == client.nick {
-				client.mutex.Lock()
-				client.user = event.Args[1]
-				client.host = event.Args[2]
-				client.mutex.Unlock()
+			if event.Nick == client.Nick() && len(event.Args) >= 2 {
+				client.setUserHost(event.Arg(0), event.Arg(1))
 			}
 
 			// This may be relevant in channels where the client resides.
 			client.handleInTargets(event.Nick, event)
 		}
 
+	// Remember the keys of channels joined with /join, which is sent as a raw command.
+	case "input.join":
+		{
+			fields := strings.Fields(event.Text)
+			if len(fields) >= 2 {
+				client.rememberJoinKeys(strings.Split(fields[0], ","), strings.Split(fields[1], ","))
+			}
+		}
+
 	// Channel join/leave/mode handling
 	case "packet.join":
 		{
 			var channel *Channel
 
 			if event.Nick == client.nick {
-				channel = &Channel{
-					id:       generateClientID("T"),
-					name:     event.Arg(0),
-					userlist: list.New(&client.isupport),
+				// Reuse a channel restored by NewFromState, since its ID should stay the same.
+				channel = client.Channel(event.Arg(0))
+				if channel != nil && channel.parted {
+					channel.userlist.Clear()
+					channel.parted = false
+				} else {
+					channel = &Channel{
+						id:       generateClientID("T"),
+						name:     event.Arg(0),
+						userlist: list.New(&client.isupport),
+					}
+					_ = client.AddTarget(channel)
+				}
+
+				client.finishJoin(channel.name)
+				if key, ok := client.takeJoinKey(channel.name); ok {
+					channel.setKey(key)
 				}
-				_ = client.AddTarget(channel)
 			} else {
 				channel = client.Channel(event.Arg(0))
+				if channel != nil {
+					client.handleNetsplitJoin(event, channel)
+				}
 			}
 
 			client.handleInTarget(channel, event)
@@ -1307,6 +1673,12 @@ func (client *Client) handleEvent(event *Event) {
 	case "packet.quit":
 		{
 			client.handleInTargets(event.Nick, event)
+			client.handleNetsplitQuit(event)
+		}
+
+	case "hook.netsplit_flush":
+		{
+			client.flushNetsplits()
 		}
 
 	case "packet.353": // NAMES
@@ -1325,6 +1697,21 @@ func (client *Client) handleEvent(event *Event) {
 			}
 		}
 
+	case "packet.403", "packet.405", "packet.470", "packet.471", "packet.473", "packet.474", "packet.475",
+		"packet.476", "packet.477", "packet.489", "packet.520": // JOIN failed
+		{
+			client.takeJoinKey(event.Arg(1))
+			client.finishJoin(event.Arg(1))
+		}
+
+	case "packet.324": // Channel modes
+		{
+			channel := client.Channel(event.Arg(1))
+			if channel != nil {
+				client.handleInTarget(channel, event)
+			}
+		}
+
 	case "packet.invite":
 		{
 			inviteeNick := event.Arg(0)
@@ -1352,24 +1739,63 @@ func (client *Client) handleEvent(event *Event) {
 				if channel != nil {
 					client.handleInTarget(channel, event)
 				}
+			} else if targetName == client.Nick() {
+				client.applyUserModes(event.Arg(1), false)
 			}
 		}
 
+	// Own user modes, account, away state and host
+	case "packet.221": // RPL_UMODEIS
+		{
+			client.applyUserModes(event.Arg(1), true)
+		}
+	case "packet.900": // RPL_LOGGEDIN
+		{
+			client.setAccount(event.Arg(2))
+		}
+	case "packet.901": // RPL_LOGGEDOUT
+		{
+			client.setAccount("")
+		}
+	case "packet.305": // RPL_UNAWAY
+		{
+			client.setAway(false)
+		}
+	case "packet.306": // RPL_NOWAWAY
+		{
+			client.setAway(true)
+		}
+	case "packet.396": // RPL_VISIBLEHOST
+		{
+			// Some servers send `user@host` instead of just the host.
+			user, host := "", event.Arg(1)
+			if at := strings.IndexByte(host, '@'); at != -1 {
+				user, host = host[:at], host[at+1:]
+			}
+
+			client.setUserHost(user, host)
+		}
+
 	// Message parsing
 	case "packet.privmsg", "ctcp.action":
 		{
 			// Target the message
 			target := Target(client.status)
-			targetName := event.Arg(0)
+			statusPrefix, targetName := client.splitStatusMsg(event.Arg(0))
+			if statusPrefix != "" {
+				event.RenderTags["statusPrefix"] = statusPrefix
+			}
 			if targetName == client.Nick() {
 				targetName = event.Nick
 			}
 
-			if event.Nick == client.nick || event.Arg(0) == client.nick {
+			// Messages sent by the client to a channel (from echo-message or znc.in/self-message) belong
+			// in the channel, not in a query.
+			if !client.isupport.IsChannel(targetName) && (event.Nick == client.nick || event.Arg(0) == client.nick) {
 				queryTarget := client.Target("query", targetName)
 				if queryTarget == nil {
 					query := &Query{
-						id: client.id,
+						id: generateClientID("T"),
 						user: list.User{
 							Nick: event.Nick,
 							User: event.User,
@@ -1380,6 +1806,12 @@ func (client *Client) handleEvent(event *Event) {
 						query.user.Account = accountTag
 					}
 
+					// Messages sent by the client (from echo-message or znc.in/self-message) have
+					// the client as the sender.
+					if event.Nick == client.nick {
+						query.user = list.User{Nick: targetName}
+					}
+
 					_ = client.AddTarget(query)
 					event.RenderTags["spawned"] = query.id
 
@@ -1404,7 +1836,10 @@ func (client *Client) handleEvent(event *Event) {
 	case "packet.notice":
 		{
 			// Find channel target
-			targetName := event.Arg(0)
+			statusPrefix, targetName := client.splitStatusMsg(event.Arg(0))
+			if statusPrefix != "" {
+				event.RenderTags["statusPrefix"] = statusPrefix
+			}
 			if client.isupport.IsChannel(targetName) {
 				channel := client.Channel(targetName)
 				if channel != nil {
@@ -1444,38 +1879,72 @@ func (client *Client) handleEvent(event *Event) {
 	case "packet.account":
 		{
 			client.handleInTargets(event.Nick, event)
+
+			if event.Nick == client.Nick() {
+				client.setAccount(event.Arg(0))
+			}
 		}
 
 	// away-notify
 	case "packet.away":
 		{
 			client.handleInTargets(event.Nick, event)
+
+			if event.Nick == client.Nick() {
+				client.setAway(event.Text != "")
+			}
+		}
+
+	// WHOIS replies
+	case "packet.311", "packet.312", "packet.313", "packet.317", "packet.318", "packet.319", "packet.330",
+		"packet.338", "packet.671", "packet.276", "packet.301", "packet.401", "packet.402":
+		{
+			client.handleWhois(event)
+		}
+
+	// LIST replies
+	case "packet.322", "packet.323", "packet.263", "packet.416":
+		{
+			client.handleList(event)
 		}
 
 	// Auto-rejoin
 	case "packet.376", "packet.422":
 		{
 			client.mutex.RLock()
-			channels := make([]string, 0, len(client.targets))
-			rejoinEvent := NewEvent("info", "rejoin")
+			requests := make([]joinRequest, 0, len(client.targets))
 			for _, target := range client.targets {
 				if channel, ok := target.(*Channel); ok {
-					channels = append(channels, channel.Name())
-
-					rejoinEvent.targets = append(rejoinEvent.targets, target)
+					requests = append(requests, joinRequest{name: channel.name, key: channel.Key(), target: channel})
 				}
 			}
 			client.mutex.RUnlock()
 
-			if len(channels) > 0 {
-				_ = client.Sendf("JOIN %s", strings.Join(channels, ","))
-				client.EmitNonBlocking(rejoinEvent)
+			if len(requests) > 0 {
+				// Only the channels that did not go over the CHANLIMIT are rejoined.
+				rejoinEvent := NewEvent("info", "rejoin")
+				for _, request := range client.sendJoins(requests, nil) {
+					rejoinEvent.targets = append(rejoinEvent.targets, request.target)
+				}
+				if len(rejoinEvent.targets) > 0 {
+					client.EmitNonBlocking(rejoinEvent)
+				}
 			}
 
 			client.mutex.Lock()
 			client.ready = true
 			client.mutex.Unlock()
 
+			// Get what was missed while disconnected from ZNC.
+			if client.CapEnabled("znc.in/playback") {
+				client.requestPlayback()
+			}
+
+			// Get the list of networks if connected to the bouncer itself.
+			if client.config.BouncerNetwork == "" && client.CapEnabled("soju.im/bouncer-networks") && client.CapEnabled("batch") {
+				client.SendQueued("BOUNCER LISTNETWORKS")
+			}
+
 			client.EmitNonBlocking(NewEvent("hook", "ready"))
 		}
 	}
@@ -1492,13 +1961,41 @@ func (client *Client) handleEvent(event *Event) {
 		client.handleInTarget(client.status, event)
 	}
 
-	client.mutex.RLock()
-	clientHandlers := client.handlers
-	client.mutex.RUnlock()
+	client.handlePlayback(event, serverTime)
+
+	client.runHandlers(event)
+}
+
+func (client *Client) runHandlers(event *Event) {
+	client.router.dispatch(event, client)
+}
+
+// capWanted returns true if the capability should be requested when the server offers it.
+func (client *Client) capWanted(key string) bool {
+	for i := range supportedCaps {
+		if supportedCaps[i] == key {
+			return true
+		}
+	}
 
-	for _, handler := range clientHandlers {
-		handler(event, client)
+	// These are only set by NewFromState, so they don't need to be locked.
+	for i := range client.extraCaps {
+		if client.extraCaps[i] == key {
+			return true
+		}
 	}
+
+	return false
+}
+
+// endCapNegotiation binds to a bouncer network if that is configured, then ends the capability
+// negotiation to let registration complete.
+func (client *Client) endCapNegotiation() {
+	if client.config.BouncerNetwork != "" && client.CapEnabled("soju.im/bouncer-networks") {
+		_ = client.Sendf("BOUNCER BIND %s", client.config.BouncerNetwork)
+	}
+
+	_ = client.Send("CAP END")
 }
 
 func (client *Client) handleInTargets(nick string, event *Event) {
@@ -0,0 +1,274 @@
+package irc
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ErrBouncerUnsupported is returned by the bouncer network functions if the server does not
+// support `soju.im/bouncer-networks` along with `batch`.
+var ErrBouncerUnsupported = errors.New("irc: server does not support soju.im/bouncer-networks")
+
+// A BouncerError is returned by the bouncer network functions if the bouncer replies with a FAIL.
+type BouncerError struct {
+	Code        string
+	Subcommand  string
+	Description string
+}
+
+func (err *BouncerError) Error() string {
+	return "irc: bouncer " + strings.ToLower(err.Subcommand) + " failed: " + err.Description + " (" + err.Code + ")"
+}
+
+// A BouncerNetwork is a network provided by a bouncer supporting `soju.im/bouncer-networks`.
+type BouncerNetwork struct {
+	ID         string            `json:"id"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// Name gets the network's name attribute.
+func (network *BouncerNetwork) Name() string {
+	return network.Attributes["name"]
+}
+
+// Host gets the network's host attribute.
+func (network *BouncerNetwork) Host() string {
+	return network.Attributes["host"]
+}
+
+// State gets the bouncer's connection state to the network, which is either "connected",
+// "connecting" or "disconnected".
+func (network *BouncerNetwork) State() string {
+	return network.Attributes["state"]
+}
+
+type bouncerRequest struct {
+	subcommand string
+	netID      string
+	result     string
+	err        error
+	done       chan struct{}
+}
+
+var escapeTags = strings.NewReplacer("\\", "\\\\", ";", "\\:", " ", "\\s", "\r", "\\r", "\n", "\\n")
+
+// BouncerNetworks gets the bouncer networks known by the client, sorted by ID. It is kept up to date
+// when `soju.im/bouncer-networks-notify` is enabled, otherwise ListBouncerNetworks must be used.
+func (client *Client) BouncerNetworks() []BouncerNetwork {
+	client.mutex.RLock()
+	networks := make([]BouncerNetwork, 0, len(client.bouncerNetworks))
+	for id, attributes := range client.bouncerNetworks {
+		network := BouncerNetwork{ID: id, Attributes: make(map[string]string, len(attributes))}
+		for key, value := range attributes {
+			network.Attributes[key] = value
+		}
+
+		networks = append(networks, network)
+	}
+	client.mutex.RUnlock()
+
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].ID < networks[j].ID
+	})
+
+	return networks
+}
+
+// ListBouncerNetworks asks the bouncer for its networks and waits for the list.
+func (client *Client) ListBouncerNetworks(ctx context.Context) ([]BouncerNetwork, error) {
+	if !client.CapEnabled("batch") {
+		return nil, ErrBouncerUnsupported
+	}
+
+	_, err := client.bouncerRequest(ctx, "LISTNETWORKS", "", "BOUNCER LISTNETWORKS")
+	if err != nil {
+		return nil, err
+	}
+
+	return client.BouncerNetworks(), nil
+}
+
+// AddBouncerNetwork adds a network to the bouncer and returns its ID. The attributes
+// should contain at least `host`.
+func (client *Client) AddBouncerNetwork(ctx context.Context, attributes map[string]string) (id string, err error) {
+	return client.bouncerRequest(ctx, "ADDNETWORK", "", "BOUNCER ADDNETWORK "+encodeBouncerAttributes(attributes))
+}
+
+// ChangeBouncerNetwork changes the attributes of a bouncer network. Attributes with an empty
+// value will be removed.
+func (client *Client) ChangeBouncerNetwork(ctx context.Context, id string, attributes map[string]string) error {
+	_, err := client.bouncerRequest(ctx, "CHANGENETWORK", id, "BOUNCER CHANGENETWORK "+id+" "+encodeBouncerAttributes(attributes))
+	return err
+}
+
+// DeleteBouncerNetwork deletes a bouncer network.
+func (client *Client) DeleteBouncerNetwork(ctx context.Context, id string) error {
+	_, err := client.bouncerRequest(ctx, "DELNETWORK", id, "BOUNCER DELNETWORK "+id)
+	return err
+}
+
+// SpawnBouncerClient creates a client bound to the bouncer network through `BOUNCER BIND` and connects
+// it to the same server as this client. The child client shares the config (including SASL credentials)
+// and handlers of this client, and it is destroyed along with it.
+func (client *Client) SpawnBouncerClient(id string) (*Client, error) {
+	client.mutex.RLock()
+	addr := client.connectAddr
+	ssl := client.connectSSL
+	config := client.config
+	client.mutex.RUnlock()
+
+	if addr == "" {
+		return nil, ErrNoConnection
+	}
+
+	config.BouncerNetwork = id
+
+	child := New(client.ctx, config)
+	for _, r := range client.router.list() {
+		child.OnPriority(r.pattern, r.priority, r.handler)
+	}
+
+	if err := child.Connect(addr, ssl); err != nil {
+		child.Destroy()
+		return nil, err
+	}
+
+	return child, nil
+}
+
+func (client *Client) bouncerRequest(ctx context.Context, subcommand, netID, line string) (string, error) {
+	if !client.CapEnabled("soju.im/bouncer-networks") {
+		return "", ErrBouncerUnsupported
+	}
+
+	request := &bouncerRequest{
+		subcommand: subcommand,
+		netID:      netID,
+		done:       make(chan struct{}),
+	}
+
+	client.mutex.Lock()
+	client.bouncerRequests = append(client.bouncerRequests, request)
+	client.mutex.Unlock()
+
+	client.SendQueued(line)
+
+	select {
+	case <-request.done:
+		return request.result, request.err
+	case <-ctx.Done():
+		client.mutex.Lock()
+		client.removeBouncerRequest(request)
+		client.mutex.Unlock()
+
+		return "", ctx.Err()
+	case <-client.ctx.Done():
+		return "", ErrDestroyed
+	}
+}
+
+// handleBouncer handles BOUNCER and FAIL BOUNCER messages.
+func (client *Client) handleBouncer(event *Event) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	if event.verb == "FAIL" {
+		// FAIL BOUNCER <code> <subcommand> [<netid>] :<description>
+		client.finishBouncerRequest(event.Arg(2), "", "", &BouncerError{
+			Code:        event.Arg(1),
+			Subcommand:  event.Arg(2),
+			Description: event.Text,
+		})
+
+		return
+	}
+
+	subcommand := strings.ToUpper(event.Arg(0))
+	netID := event.Arg(1)
+
+	switch subcommand {
+	case "NETWORK":
+		attributes := event.Arg(2)
+		if attributes == "*" {
+			delete(client.bouncerNetworks, netID)
+			break
+		}
+
+		network := client.bouncerNetworks[netID]
+		if network == nil {
+			network = make(map[string]string, 8)
+			client.bouncerNetworks[netID] = network
+		}
+
+		for key, value := range decodeBouncerAttributes(attributes) {
+			if value == "" {
+				delete(network, key)
+			} else {
+				network[key] = value
+			}
+		}
+	case "ADDNETWORK":
+		client.finishBouncerRequest(subcommand, "", netID, nil)
+	case "CHANGENETWORK", "DELNETWORK":
+		client.finishBouncerRequest(subcommand, netID, netID, nil)
+	}
+}
+
+// finishBouncerRequest resolves the oldest pending request for the subcommand. The client mutex must be held.
+func (client *Client) finishBouncerRequest(subcommand, netID, result string, err error) {
+	for _, request := range client.bouncerRequests {
+		if request.subcommand == subcommand && (netID == "" || request.netID == netID) {
+			request.result = result
+			request.err = err
+			close(request.done)
+
+			client.removeBouncerRequest(request)
+			return
+		}
+	}
+}
+
+// removeBouncerRequest removes a pending request. The client mutex must be held.
+func (client *Client) removeBouncerRequest(request *bouncerRequest) {
+	for i := range client.bouncerRequests {
+		if client.bouncerRequests[i] == request {
+			client.bouncerRequests = append(client.bouncerRequests[:i], client.bouncerRequests[i+1:]...)
+			return
+		}
+	}
+}
+
+func encodeBouncerAttributes(attributes map[string]string) string {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tokens := make([]string, 0, len(keys))
+	for _, key := range keys {
+		tokens = append(tokens, key+"="+escapeTags.Replace(attributes[key]))
+	}
+
+	return strings.Join(tokens, ";")
+}
+
+func decodeBouncerAttributes(s string) map[string]string {
+	attributes := make(map[string]string, 8)
+	for _, token := range strings.Split(s, ";") {
+		if token == "" {
+			continue
+		}
+
+		kv := strings.SplitN(token, "=", 2)
+		if len(kv) == 2 {
+			attributes[kv[0]] = unescapeTags.Replace(kv[1])
+		} else {
+			attributes[kv[0]] = ""
+		}
+	}
+
+	return attributes
+}
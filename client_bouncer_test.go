@@ -0,0 +1,130 @@
+package irc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/internal/irctest"
+)
+
+func TestClient_BouncerNetworks(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+
+	results := make(chan error, 1)
+	var addedID string
+
+	interaction := irctest.Interaction{
+		Lines: []irctest.InteractionLine{
+			{Client: "CAP LS 302"},
+			{Client: "NICK Test"},
+			{Client: "USER Tester 8 * :..."},
+			{Server: ":bouncer.example.com CAP * LS :batch soju.im/bouncer-networks soju.im/bouncer-networks-notify"},
+			{Client: "CAP REQ :batch soju.im/bouncer-networks soju.im/bouncer-networks-notify"},
+			{Server: ":bouncer.example.com CAP * ACK :batch soju.im/bouncer-networks soju.im/bouncer-networks-notify"},
+			{Client: "CAP END"},
+			{Server: ":bouncer.example.com 001 Test :Welcome to soju, Test"},
+			{Server: ":bouncer.example.com 422 Test :No MOTD"},
+			{Client: "BOUNCER LISTNETWORKS"},
+			{Server: ":bouncer.example.com BATCH +a soju.im/bouncer-networks"},
+			{Server: "@batch=a :bouncer.example.com BOUNCER NETWORK 1 name=Libera;host=irc.libera.chat;state=connected"},
+			{Server: "@batch=a :bouncer.example.com BOUNCER NETWORK 2 name=OFTC\\sNet;state=disconnected"},
+			{Server: ":bouncer.example.com BATCH -a"},
+			{Server: "PING :bouncer.example.com"},
+			{Client: "PONG :bouncer.example.com"},
+			{Callback: func() error {
+				networks := client.BouncerNetworks()
+				if len(networks) != 2 {
+					return errors.New("there should be two networks")
+				}
+				if networks[0].Host() != "irc.libera.chat" || networks[0].State() != "connected" {
+					return errors.New("wrong attributes on first network")
+				}
+				if networks[1].Name() != "OFTC Net" {
+					return errors.New("wrong name on second network: " + networks[1].Name())
+				}
+
+				go func() {
+					ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+					defer cancel()
+
+					var err error
+					addedID, err = client.AddBouncerNetwork(ctx, map[string]string{"name": "Test Net", "host": "irc.example.com"})
+					results <- err
+				}()
+
+				return nil
+			}},
+			{Client: "BOUNCER ADDNETWORK host=irc.example.com;name=Test\\sNet"},
+			{Server: ":bouncer.example.com BOUNCER NETWORK 3 host=irc.example.com;name=Test\\sNet;state=connecting"},
+			{Server: ":bouncer.example.com BOUNCER ADDNETWORK 3"},
+			{Callback: func() error {
+				if err := <-results; err != nil {
+					return err
+				}
+				if addedID != "3" {
+					return errors.New("wrong ID: " + addedID)
+				}
+
+				go func() {
+					results <- client.DeleteBouncerNetwork(context.Background(), "9")
+				}()
+
+				return nil
+			}},
+			{Client: "BOUNCER DELNETWORK 9"},
+			{Server: ":bouncer.example.com FAIL BOUNCER INVALID_NETID DELNETWORK 9 :Network not found"},
+			{Server: ":bouncer.example.com BOUNCER NETWORK 2 *"},
+			{Server: "PING :bouncer.example.com"},
+			{Client: "PONG :bouncer.example.com"},
+			{Callback: func() error {
+				err, ok := (<-results).(*irc.BouncerError)
+				if !ok || err.Code != "INVALID_NETID" {
+					return errors.New("expected INVALID_NETID bouncer error")
+				}
+
+				networks := client.BouncerNetworks()
+				if len(networks) != 2 || networks[0].ID != "1" || networks[1].ID != "3" {
+					return errors.New("network 2 should have been removed")
+				}
+
+				return nil
+			}},
+		},
+	}
+
+	runInteraction(t, client, &interaction)
+}
+
+func TestClient_BouncerBind(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:           "Test",
+		User:           "Tester",
+		RealName:       "...",
+		SendRate:       1000,
+		BouncerNetwork: "42",
+	})
+
+	interaction := irctest.Interaction{
+		Strict: true,
+		Lines: []irctest.InteractionLine{
+			{Client: "CAP LS 302"},
+			{Client: "NICK Test"},
+			{Client: "USER Tester 8 * :..."},
+			{Server: ":bouncer.example.com CAP * LS :soju.im/bouncer-networks"},
+			{Client: "CAP REQ :soju.im/bouncer-networks"},
+			{Server: ":bouncer.example.com CAP * ACK :soju.im/bouncer-networks"},
+			{Client: "BOUNCER BIND 42"},
+			{Client: "CAP END"},
+		},
+	}
+
+	runInteraction(t, client, &interaction)
+}
@@ -0,0 +1,155 @@
+package irc
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gissleh/irc/isupport"
+)
+
+// maxJoinLength is the longest JOIN line that will be sent, excluding the CRLF.
+const maxJoinLength = 510
+
+// A joinRequest is a channel to join, with the target if it's a rejoin.
+type joinRequest struct {
+	name   string
+	key    string
+	target Target
+}
+
+// sendJoins joins the channels in as few JOIN lines as the line length and TARGMAX allows, and
+// queues them. The channels that would go over the CHANLIMIT, counting the joined channels and the
+// ones still waiting for the server's reply, are not joined but reported with an error event on
+// their target with the channel name as the argument. The requests that were sent are returned.
+func (client *Client) sendJoins(requests []joinRequest, joined []string) []joinRequest {
+	counted := make(map[string]bool, len(joined))
+	for _, name := range joined {
+		counted[strings.ToLower(name)] = true
+	}
+	client.mutex.RLock()
+	for name := range client.pendingJoins {
+		counted[name] = true
+	}
+	client.mutex.RUnlock()
+
+	limits := client.isupport.ChanLimits()
+	counts := make([]int, len(limits))
+	for name := range counted {
+		if i := chanLimitIndex(limits, name); i != -1 {
+			counts[i]++
+		}
+	}
+
+	keyed := make([]joinRequest, 0, len(requests))
+	unkeyed := make([]joinRequest, 0, len(requests))
+	for _, request := range requests {
+		// Channels that are already counted don't take up another one.
+		if i := chanLimitIndex(limits, request.name); i != -1 && !counted[strings.ToLower(request.name)] {
+			if counts[i] >= limits[i].Limit {
+				event := NewErrorEventTarget(request.target, "join", "Cannot join "+request.name+", as the server only allows "+strconv.Itoa(limits[i].Limit)+" channels", "join_chanlimit", nil)
+				event.Args = append(event.Args, request.name)
+				client.EmitNonBlocking(event)
+				continue
+			}
+
+			counts[i]++
+			counted[strings.ToLower(request.name)] = true
+		}
+
+		// Channels with keys must come first, as the keys are matched by position.
+		if request.key != "" {
+			keyed = append(keyed, request)
+		} else {
+			unkeyed = append(unkeyed, request)
+		}
+	}
+
+	sent := append(keyed, unkeyed...)
+	client.mutex.Lock()
+	for _, request := range sent {
+		client.pendingJoins[strings.ToLower(request.name)] = true
+	}
+	client.mutex.Unlock()
+
+	maxTargets, _ := client.isupport.TargMax("JOIN")
+	for _, line := range joinLines(sent, maxTargets) {
+		client.SendQueued(line)
+	}
+
+	return sent
+}
+
+// finishJoin stops counting the channel as a pending join, as the server has either confirmed or
+// rejected it.
+func (client *Client) finishJoin(name string) {
+	client.mutex.Lock()
+	delete(client.pendingJoins, strings.ToLower(name))
+	client.mutex.Unlock()
+}
+
+// joinLines splits the joins into lines with at most maxTargets channels each (0 for no limit). The
+// keyed channels must be first.
+func joinLines(requests []joinRequest, maxTargets int) []string {
+	lines := make([]string, 0, 1)
+	channels := make([]string, 0, len(requests))
+	keys := make([]string, 0, len(requests))
+	length := 0
+
+	flush := func() {
+		if len(channels) == 0 {
+			return
+		}
+
+		if len(keys) > 0 {
+			lines = append(lines, "JOIN "+strings.Join(channels, ",")+" "+strings.Join(keys, ","))
+		} else {
+			lines = append(lines, "JOIN "+strings.Join(channels, ","))
+		}
+
+		channels = channels[:0]
+		keys = keys[:0]
+	}
+
+	for _, request := range requests {
+		// The length of "JOIN chan,chan key,key" with this channel added.
+		newLength := len("JOIN ") + length + len(request.name)
+		if len(channels) > 0 {
+			newLength++
+		}
+		if request.key != "" {
+			newLength += len(request.key) + 1
+		}
+
+		if len(channels) > 0 && ((maxTargets > 0 && len(channels) >= maxTargets) || newLength > maxJoinLength) {
+			flush()
+		}
+
+		channels = append(channels, request.name)
+		if request.key != "" {
+			keys = append(keys, request.key)
+		}
+
+		length = len(strings.Join(channels, ","))
+		if len(keys) > 0 {
+			length += len(strings.Join(keys, ",")) + 1
+		}
+	}
+	flush()
+
+	return lines
+}
+
+// chanLimitIndex finds the limit that applies to the channel, or -1 if there is none.
+func chanLimitIndex(limits []isupport.ChanLimit, name string) int {
+	if name == "" {
+		return -1
+	}
+
+	for i, limit := range limits {
+		if limit.Limit > 0 && strings.IndexByte(limit.Prefixes, name[0]) != -1 {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,135 @@
+package irc_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/internal/irctest"
+)
+
+func TestClient_Join(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+
+	joinErrors := client.Subscribe(context.Background(), irc.EventFilter{Patterns: []string{"error.join"}})
+	rejoins := client.Subscribe(context.Background(), irc.EventFilter{Patterns: []string{"info.rejoin"}})
+
+	longNames := make([]string, 10)
+	longNameErrors := make([]irctest.InteractionLine, 0, len(longNames))
+	for i := range longNames {
+		longNames[i] = "#" + strings.Repeat(string(rune('a'+i)), 59)
+		longNameErrors = append(longNameErrors, irctest.InteractionLine{Server: ":testserver.example.com 403 Test " + longNames[i] + " :No such channel"})
+	}
+
+	interaction := irctest.Interaction{
+		Strict: true,
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Server: ":testserver.example.com 005 Test TARGMAX=JOIN:3,PRIVMSG:4 CHANLIMIT=#:4 :are supported by this server"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+			irctest.InteractionLine{Callback: func() error {
+				client.Join("#a", "#b", "#c")
+				client.Join("#d", "#e")
+				return nil
+			}},
+			irctest.InteractionLine{Client: "JOIN #a,#b,#c"},
+			irctest.InteractionLine{Client: "JOIN #d"},
+			irctest.InteractionLine{Server: ":testserver.example.com 471 Test #d :Cannot join channel (+l)"},
+			irctest.InteractionLine{Server: ":testserver.example.com 005 Test TARGMAX=PRIVMSG:4 CHANLIMIT=#:100 :are supported by this server"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+			irctest.InteractionLine{Callback: func() error {
+				client.Join(longNames...)
+				return nil
+			}},
+			irctest.InteractionLine{Client: "JOIN " + strings.Join(longNames[:8], ",")},
+			irctest.InteractionLine{Client: "JOIN " + strings.Join(longNames[8:], ",")},
+		),
+	}
+	interaction.Lines = append(interaction.Lines, longNameErrors...)
+	interaction.Lines = append(interaction.Lines,
+		irctest.InteractionLine{Server: ":Test!~Tester@127.0.0.1 JOIN #a"},
+		irctest.InteractionLine{Server: ":Test!~Tester@127.0.0.1 JOIN #b"},
+		irctest.InteractionLine{Server: ":Test!~Tester@127.0.0.1 JOIN #c"},
+		irctest.InteractionLine{Server: ":testserver.example.com 005 Test CHANLIMIT=#:2 :are supported by this server"},
+		irctest.InteractionLine{Server: ":testserver.example.com 376 Test :End of /MOTD command."},
+		irctest.InteractionLine{Client: "JOIN #a,#b"},
+		irctest.InteractionLine{Server: "PING :testserver.example.com"},
+		irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+	)
+	runInteraction(t, client, &interaction)
+
+	expected := []struct {
+		name     string
+		targeted bool
+	}{
+		{"#e", false},
+		{"#c", true},
+	}
+	for _, row := range expected {
+		select {
+		case event := <-joinErrors:
+			if event.Arg(0) != row.name {
+				t.Errorf("Error should be for %s, got %#+v", row.name, event.Args)
+			}
+
+			target := event.ChannelTarget()
+			if !row.targeted && target != nil {
+				t.Errorf("Error for %s should not have a target", target.Name())
+			} else if row.targeted && (target == nil || target.Name() != row.name) {
+				t.Errorf("Error should be targeted at %s", row.name)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for error.join")
+		}
+	}
+
+	select {
+	case event := <-rejoins:
+		ids := []string{client.Channel("#a").ID(), client.Channel("#b").ID()}
+		if strings.Join(event.TargetIDs(), ",") != strings.Join(ids, ",") {
+			t.Errorf("Rejoin should only list the rejoined channels, got %#+v", event.TargetIDs())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for info.rejoin")
+	}
+}
+
+func TestClient_JoinFailed(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+
+	interaction := irctest.Interaction{
+		Strict: true,
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Server: ":testserver.example.com 005 Test CHANLIMIT=#:1 :are supported by this server"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+			irctest.InteractionLine{Callback: func() error {
+				client.Join("#registered")
+				return nil
+			}},
+			irctest.InteractionLine{Client: "JOIN #registered"},
+			irctest.InteractionLine{Server: ":testserver.example.com 477 Test #registered :You need to be identified to a registered account to join this channel"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+			irctest.InteractionLine{Callback: func() error {
+				client.Join("#open")
+				return nil
+			}},
+			irctest.InteractionLine{Client: "JOIN #open"},
+		),
+	}
+	runInteraction(t, client, &interaction)
+}
@@ -0,0 +1,88 @@
+package irc
+
+import (
+	mathRand "math/rand"
+	"strconv"
+	"time"
+)
+
+// Lag gets the round trip time of the last PING, or 0 if none has been answered since connecting.
+func (client *Client) Lag() time.Duration {
+	client.mutex.RLock()
+	defer client.mutex.RUnlock()
+
+	return client.lag
+}
+
+// keepaliveInterval gets how often the keepalive should be checked, which is often enough to
+// notice a timeout or send a PING without being much late.
+func (client *Client) keepaliveInterval() time.Duration {
+	interval := client.config.PingInterval
+	if client.config.PingTimeout < interval {
+		interval = client.config.PingTimeout
+	}
+
+	interval /= 4
+	if interval > time.Second*5 {
+		interval = time.Second * 5
+	}
+	if interval < time.Millisecond*10 {
+		interval = time.Millisecond * 10
+	}
+
+	return interval
+}
+
+// handleKeepalive closes the connection if the server has been silent for longer than the
+// PingTimeout, and sends a PING every PingInterval once registered.
+func (client *Client) handleKeepalive() {
+	now := time.Now()
+
+	client.mutex.Lock()
+	if client.conn == nil {
+		client.mutex.Unlock()
+		return
+	}
+
+	silence := now.Sub(client.lastReceive)
+	if silence > client.config.PingTimeout {
+		client.mutex.Unlock()
+
+		client.EmitNonBlocking(NewErrorEvent("timeout", "Ping timeout: "+strconv.Itoa(int(silence.Seconds()))+" seconds", "ping_timeout", nil))
+		_ = client.Disconnect(false)
+		return
+	}
+
+	// A PING that's not answered is sent again after the timeout, in case only the PONG was lost.
+	waiting := client.pingToken != "" && now.Sub(client.pingSent) < client.config.PingTimeout
+	if client.nick == "" || waiting || now.Sub(client.pingSent) < client.config.PingInterval {
+		client.mutex.Unlock()
+		return
+	}
+
+	client.pingToken = strconv.FormatInt(mathRand.Int63(), 36)
+	client.pingSent = now
+	token := client.pingToken
+	client.mutex.Unlock()
+
+	_ = client.Sendf("PING :%s", token)
+}
+
+// handlePong measures the lag if the token is from the last PING, and emits `client.lag` with the
+// lag in milliseconds as the argument.
+func (client *Client) handlePong(token string) {
+	client.mutex.Lock()
+	if client.pingToken == "" || token != client.pingToken {
+		client.mutex.Unlock()
+		return
+	}
+
+	client.lag = time.Since(client.pingSent)
+	client.pingToken = ""
+	lag := client.lag
+	client.mutex.Unlock()
+
+	event := NewEvent("client", "lag")
+	event.Args = append(event.Args, strconv.FormatInt(int64(lag/time.Millisecond), 10))
+	client.EmitNonBlocking(event)
+}
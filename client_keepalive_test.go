@@ -0,0 +1,79 @@
+package irc_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gissleh/irc"
+)
+
+func TestClient_Keepalive(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Listen:", err)
+	}
+	defer listener.Close()
+
+	// The server answers the first PING, and then goes silent.
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		pongs := 0
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+
+			if strings.HasPrefix(line, "USER ") {
+				_, _ = conn.Write([]byte(":testserver.example.com 001 Test :Welcome\r\n"))
+			} else if strings.HasPrefix(line, "PING :") && pongs == 0 {
+				pongs++
+				time.Sleep(time.Millisecond * 20)
+				_, _ = conn.Write([]byte(":testserver.example.com PONG testserver.example.com :" + line[6:] + "\r\n"))
+			}
+		}
+	}()
+
+	client := irc.New(context.Background(), irc.Config{
+		Nick:         "Test",
+		User:         "Tester",
+		RealName:     "...",
+		PingInterval: time.Millisecond * 100,
+		PingTimeout:  time.Millisecond * 500,
+	})
+
+	events := client.Subscribe(context.Background(), irc.EventFilter{Patterns: []string{"client.lag", "error.timeout", "client.disconnect"}})
+
+	if err := client.Connect(listener.Addr().String(), false); err != nil {
+		t.Fatal("Connect:", err)
+	}
+
+	for _, name := range []string{"client.lag", "error.timeout", "client.disconnect"} {
+		select {
+		case event := <-events:
+			if event.Name() != name {
+				t.Fatalf("Expected %s, got %s", name, event.Name())
+			}
+			if name == "client.lag" && (client.Lag() < time.Millisecond*20 || event.Arg(0) == "") {
+				t.Errorf("Lag should be at least 20ms, not %s", client.Lag())
+			}
+		case <-time.After(time.Second * 2):
+			t.Fatalf("Timed out waiting for %s", name)
+		}
+	}
+
+	if client.Connected() {
+		t.Error("Client should be disconnected after the ping timeout")
+	}
+}
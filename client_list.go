@@ -0,0 +1,318 @@
+package irc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrListFilterUnsupported is returned by Client.List if the filter requires an ELIST extension the
+// server does not support.
+var ErrListFilterUnsupported = errors.New("irc: list filter not supported by server")
+
+// ErrTryAgain is returned by Client.List if the server refuses to list channels at the moment.
+var ErrTryAgain = errors.New("irc: server load is too heavy, try again later")
+
+// ErrTooManyMatches is returned by Client.List if the server refuses to list that many channels.
+var ErrTooManyMatches = errors.New("irc: too many matches")
+
+// ListFilter is used by Client.List to limit which channels are listed. The zero value lists all
+// channels. Only the user count can be filtered by the client if the server does not support
+// the ELIST extension for it, the rest will cause ErrListFilterUnsupported.
+type ListFilter struct {
+	// Masks limits the listing to channels matching any of the masks. Masks with wildcards
+	// require ELIST=M, while plain channel names are always supported.
+	Masks []string
+
+	// ExcludeMasks excludes channels matching any of the masks. It requires ELIST=N.
+	ExcludeMasks []string
+
+	// MinUsers and MaxUsers filters by user count, inclusively. It uses ELIST=U if
+	// available. Zero means no limit.
+	MinUsers int
+	MaxUsers int
+
+	// CreatedNewerThan and CreatedOlderThan filters by how long ago the channel was
+	// created, with minute precision. It requires ELIST=C.
+	CreatedNewerThan time.Duration
+	CreatedOlderThan time.Duration
+
+	// TopicNewerThan and TopicOlderThan filters by how long ago the topic was set, with
+	// minute precision. It requires ELIST=T.
+	TopicNewerThan time.Duration
+	TopicOlderThan time.Duration
+}
+
+// ListEntry is a channel listed by Client.List.
+type ListEntry struct {
+	Channel string `json:"channel"`
+	Users   int    `json:"users"`
+	Modes   string `json:"modes,omitempty"`
+	Topic   string `json:"topic"`
+}
+
+type listRequest struct {
+	callback func(entry ListEntry)
+	minUsers int
+	maxUsers int
+	err      error
+	done     chan struct{}
+}
+
+// List sends a LIST with the filter and calls the callback for every channel listed, returning
+// once the list is complete. The callback is called from the event loop, so it should not block;
+// use ListEntries to receive the entries on a channel instead.
+// Only one listing can run at a time, and concurrent calls will wait for their turn. If the context
+// is cancelled, the rest of that listing is discarded when it arrives.
+func (client *Client) List(ctx context.Context, filter ListFilter, callback func(entry ListEntry)) error {
+	if !client.Connected() {
+		return ErrNoConnection
+	}
+
+	params, localFilter, err := client.listParams(filter)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case client.listLock <- struct{}{}:
+		defer func() { <-client.listLock }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	request := &listRequest{
+		callback: callback,
+		done:     make(chan struct{}),
+	}
+	if localFilter {
+		request.minUsers = filter.MinUsers
+		request.maxUsers = filter.MaxUsers
+	}
+
+	client.mutex.Lock()
+	client.listRequest = request
+	client.mutex.Unlock()
+
+	if params != "" {
+		client.SendQueuedf("LIST %s", params)
+	} else {
+		client.SendQueued("LIST")
+	}
+
+	select {
+	case <-request.done:
+		return request.err
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-client.ctx.Done():
+		err = ErrDestroyed
+	}
+
+	client.mutex.Lock()
+	if client.listRequest == request {
+		client.listRequest = nil
+		client.listSkip++
+	}
+	client.mutex.Unlock()
+
+	return err
+}
+
+// ListEntries is like List, but the entries are sent on the returned channel so that they can be
+// handled without blocking the event loop. The channel is closed once the listing is done, and the
+// error from List is then sent on the error channel. The entries are queued until they are received,
+// so the channel must be drained, or the context cancelled.
+func (client *Client) ListEntries(ctx context.Context, filter ListFilter) (<-chan ListEntry, <-chan error) {
+	entries := make(chan ListEntry, 64)
+	errs := make(chan error, 1)
+
+	mutex := sync.Mutex{}
+	queue := make([]ListEntry, 0, 64)
+	finished := false
+	var listErr error
+	wake := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+
+	go func() {
+		err := client.List(ctx, filter, func(entry ListEntry) {
+			mutex.Lock()
+			queue = append(queue, entry)
+			mutex.Unlock()
+			notify()
+		})
+
+		mutex.Lock()
+		finished = true
+		listErr = err
+		mutex.Unlock()
+		notify()
+	}()
+
+	go func() {
+		defer close(entries)
+
+		for {
+			mutex.Lock()
+			batch := queue
+			queue = make([]ListEntry, 0, 64)
+			done := finished
+			err := listErr
+			mutex.Unlock()
+
+			for _, entry := range batch {
+				select {
+				case entries <- entry:
+				case <-ctx.Done():
+					errs <- ctx.Err()
+					return
+				}
+			}
+
+			if done && len(batch) == 0 {
+				errs <- err
+				return
+			}
+			if len(batch) == 0 {
+				<-wake
+			}
+		}
+	}()
+
+	return entries, errs
+}
+
+// listParams builds the LIST parameters for the filter. If localFilter is true, the user count
+// must be checked by the client.
+func (client *Client) listParams(filter ListFilter) (params string, localFilter bool, err error) {
+	elist := client.isupport.EList()
+	conditions := make([]string, 0, 8)
+
+	if filter.MinUsers > 0 || filter.MaxUsers > 0 {
+		if strings.Contains(elist, "U") {
+			if filter.MinUsers > 0 {
+				conditions = append(conditions, ">"+strconv.Itoa(filter.MinUsers-1))
+			}
+			if filter.MaxUsers > 0 {
+				conditions = append(conditions, "<"+strconv.Itoa(filter.MaxUsers+1))
+			}
+		} else {
+			localFilter = true
+		}
+	}
+
+	timeConditions := []struct {
+		ext      string
+		op       string
+		duration time.Duration
+	}{
+		{"C", "<", filter.CreatedNewerThan},
+		{"C", ">", filter.CreatedOlderThan},
+		{"T", "<", filter.TopicNewerThan},
+		{"T", ">", filter.TopicOlderThan},
+	}
+	for _, condition := range timeConditions {
+		if condition.duration <= 0 {
+			continue
+		}
+		if !strings.Contains(elist, condition.ext) {
+			return "", false, ErrListFilterUnsupported
+		}
+
+		minutes := int(condition.duration / time.Minute)
+		conditions = append(conditions, condition.ext+condition.op+strconv.Itoa(minutes))
+	}
+
+	for _, mask := range filter.Masks {
+		if strings.ContainsAny(mask, "*?") && !strings.Contains(elist, "M") {
+			return "", false, ErrListFilterUnsupported
+		}
+
+		conditions = append(conditions, mask)
+	}
+
+	if len(filter.ExcludeMasks) > 0 && !strings.Contains(elist, "N") {
+		return "", false, ErrListFilterUnsupported
+	}
+	for _, mask := range filter.ExcludeMasks {
+		conditions = append(conditions, "!"+mask)
+	}
+
+	return strings.Join(conditions, ","), localFilter, nil
+}
+
+// handleList handles the LIST replies for the pending request. The replies to cancelled requests
+// are skipped, as the server still sends them.
+func (client *Client) handleList(event *Event) {
+	client.mutex.Lock()
+	if client.listSkip > 0 {
+		if isListEnd(event) {
+			client.listSkip--
+		}
+
+		client.mutex.Unlock()
+		return
+	}
+
+	request := client.listRequest
+	if request == nil {
+		client.mutex.Unlock()
+		return
+	}
+
+	if isListEnd(event) {
+		switch event.verb {
+		case "263":
+			request.err = ErrTryAgain
+		case "416":
+			request.err = ErrTooManyMatches
+		}
+
+		client.listRequest = nil
+		close(request.done)
+	}
+	client.mutex.Unlock()
+
+	if event.verb != "322" {
+		return
+	}
+
+	// <client> <channel> <client count> :<topic>
+	entry := ListEntry{Channel: event.Arg(1), Topic: event.Text}
+	entry.Users, _ = strconv.Atoi(event.Arg(2))
+	if (request.minUsers > 0 && entry.Users < request.minUsers) || (request.maxUsers > 0 && entry.Users > request.maxUsers) {
+		return
+	}
+
+	// Some servers put the channel modes in front of the topic.
+	if strings.HasPrefix(entry.Topic, "[+") {
+		if end := strings.Index(entry.Topic, "]"); end != -1 {
+			entry.Modes = entry.Topic[1:end]
+			entry.Topic = strings.TrimLeft(entry.Topic[end+1:], " ")
+		}
+	}
+
+	if request.callback != nil {
+		request.callback(entry)
+	}
+}
+
+// isListEnd returns true if the event is the last reply to a LIST.
+func isListEnd(event *Event) bool {
+	switch event.verb {
+	case "323": // <client> :End of /LIST
+		return true
+	case "263", "416": // <client> <command> :<info>
+		return strings.EqualFold(event.Arg(1), "LIST")
+	}
+
+	return false
+}
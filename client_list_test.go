@@ -0,0 +1,167 @@
+package irc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/internal/irctest"
+)
+
+func TestClient_List(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+
+	var entries, staleEntries, newEntries []irc.ListEntry
+	listErr := make(chan error, 1)
+
+	lines := irctest.Registration("Test", "Tester", "...")
+	lines = append(lines,
+		irctest.InteractionLine{Callback: func() error {
+			err := client.List(context.Background(), irc.ListFilter{ExcludeMasks: []string{"#spam*"}}, nil)
+			if err != irc.ErrListFilterUnsupported {
+				return errors.New("ELIST=N is not supported by the server")
+			}
+
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+				defer cancel()
+
+				listErr <- client.List(ctx, irc.ListFilter{
+					Masks:          []string{"#Test", "#Test2"},
+					MinUsers:       2,
+					TopicOlderThan: time.Hour,
+				}, func(entry irc.ListEntry) {
+					entries = append(entries, entry)
+				})
+			}()
+
+			return nil
+		}},
+		irctest.InteractionLine{Client: "LIST >1,T>60,#Test,#Test2"},
+		irctest.InteractionLine{Server: ":testserver.example.com 321 Test Channel :Users  Name"},
+		irctest.InteractionLine{Server: ":testserver.example.com 322 Test #Test 4 :[+nt] Test Channel"},
+		irctest.InteractionLine{Server: ":testserver.example.com 322 Test #Test2 2 :[+ntl 50]"},
+		irctest.InteractionLine{Server: ":testserver.example.com 323 Test :End of /LIST"},
+		irctest.InteractionLine{Callback: func() error {
+			if err := <-listErr; err != nil {
+				return err
+			}
+
+			expected := []irc.ListEntry{
+				{Channel: "#Test", Users: 4, Modes: "+nt", Topic: "Test Channel"},
+				{Channel: "#Test2", Users: 2, Modes: "+ntl 50", Topic: ""},
+			}
+			if !reflect.DeepEqual(entries, expected) {
+				t.Logf("Expected: %#+v", expected)
+				t.Logf("Result:   %#+v", entries)
+				return errors.New("list entries does not match")
+			}
+
+			return nil
+		}},
+		irctest.InteractionLine{Callback: func() error {
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				listErr <- client.List(ctx, irc.ListFilter{}, func(entry irc.ListEntry) {
+					staleEntries = append(staleEntries, entry)
+				})
+			}()
+			time.Sleep(time.Millisecond * 50)
+			cancel()
+			if err := <-listErr; err != context.Canceled {
+				return fmt.Errorf("cancelled list should return context.Canceled, got %v", err)
+			}
+
+			go func() {
+				listErr <- client.List(context.Background(), irc.ListFilter{Masks: []string{"#New"}}, func(entry irc.ListEntry) {
+					newEntries = append(newEntries, entry)
+				})
+			}()
+
+			return nil
+		}},
+		irctest.InteractionLine{Client: "LIST"},
+		irctest.InteractionLine{Client: "LIST #New"},
+		irctest.InteractionLine{Server: ":testserver.example.com 322 Test #Old 3 :Stale"},
+		irctest.InteractionLine{Server: ":testserver.example.com 323 Test :End of /LIST"},
+		irctest.InteractionLine{Server: ":testserver.example.com 322 Test #New 5 :Fresh"},
+		irctest.InteractionLine{Server: ":testserver.example.com 323 Test :End of /LIST"},
+		irctest.InteractionLine{Callback: func() error {
+			if err := <-listErr; err != nil {
+				return err
+			}
+
+			if len(staleEntries) != 0 {
+				return errors.New("cancelled list should not get any entries")
+			}
+			if len(newEntries) != 1 || newEntries[0].Channel != "#New" {
+				t.Logf("Result: %#+v", newEntries)
+				return errors.New("list after a cancelled one should only get its own entries")
+			}
+
+			return nil
+		}},
+	)
+
+	runInteraction(t, client, &irctest.Interaction{Lines: lines})
+}
+
+func TestClient_ListEntries(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	var entries <-chan irc.ListEntry
+	var listErr <-chan error
+
+	lines := irctest.Registration("Test", "Tester", "...")
+	lines = append(lines,
+		irctest.InteractionLine{Callback: func() error {
+			entries, listErr = client.ListEntries(ctx, irc.ListFilter{})
+			return nil
+		}},
+		irctest.InteractionLine{Client: "LIST"},
+		irctest.InteractionLine{Server: ":testserver.example.com 321 Test Channel :Users  Name"},
+		irctest.InteractionLine{Server: ":testserver.example.com 322 Test #Test 4 :[+nt] Test Channel"},
+		irctest.InteractionLine{Server: ":testserver.example.com 322 Test #Test2 2 :[+ntl 50]"},
+		irctest.InteractionLine{Server: ":testserver.example.com 323 Test :End of /LIST"},
+		irctest.InteractionLine{Callback: func() error {
+			result := make([]irc.ListEntry, 0, 2)
+			for entry := range entries {
+				result = append(result, entry)
+			}
+			if err := <-listErr; err != nil {
+				return err
+			}
+
+			expected := []irc.ListEntry{
+				{Channel: "#Test", Users: 4, Modes: "+nt", Topic: "Test Channel"},
+				{Channel: "#Test2", Users: 2, Modes: "+ntl 50", Topic: ""},
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Logf("Expected: %#+v", expected)
+				t.Logf("Result:   %#+v", result)
+				return errors.New("list entries does not match")
+			}
+
+			return nil
+		}},
+	)
+
+	runInteraction(t, client, &irctest.Interaction{Lines: lines})
+}
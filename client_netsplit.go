@@ -0,0 +1,169 @@
+package irc
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// netsplitDelay is how long to wait after the last split quit or join before the grouped events are emitted.
+const netsplitDelay = time.Second
+
+// netsplitMemory is how long a nick that quit in a netsplit is remembered, so that its rejoin is seen as
+// a netjoin.
+const netsplitMemory = time.Minute * 15
+
+// netsplitQuitMessage matches quit messages like `hub.example.com leaf.example.com`.
+var netsplitQuitMessage = regexp.MustCompile(`^([^\s.:/]+\.[^\s:/]+) ([^\s.:/]+\.[^\s:/]+)$`)
+
+// netsplitState groups the quits and joins of netsplits and netjoins. It's only used from the event loop.
+type netsplitState struct {
+	pending []*netsplitGroup
+	nicks   map[string]netsplitNick
+	timer   *time.Timer
+}
+
+// A netsplitGroup is the nicks that quit or joined a channel in a netsplit or netjoin between two servers.
+type netsplitGroup struct {
+	verb    string
+	servers string
+	channel *Channel
+	nicks   []string
+}
+
+type netsplitNick struct {
+	servers string
+	time    time.Time
+}
+
+// handleNetsplitQuit hides the quit and adds it to the `info.netsplit` events if it's part of a netsplit.
+// The event must have been handled in the targets.
+func (client *Client) handleNetsplitQuit(event *Event) {
+	if event.Nick == client.Nick() {
+		return
+	}
+
+	servers := client.netsplitBatchServers(event, "netsplit")
+	if servers == "" {
+		if match := netsplitQuitMessage.FindStringSubmatch(event.Text); match != nil && match[1] != match[2] {
+			servers = match[1] + " " + match[2]
+		}
+	}
+	if servers == "" {
+		return
+	}
+
+	event.Hide()
+
+	client.netsplit.nicks[strings.ToLower(event.Nick)] = netsplitNick{servers: servers, time: time.Now()}
+	for _, target := range event.targets {
+		if channel, ok := target.(*Channel); ok {
+			client.addNetsplitNick("netsplit", servers, channel, event.Nick)
+		}
+	}
+
+	client.scheduleNetsplitFlush(event)
+}
+
+// handleNetsplitJoin hides the join and adds it to the `info.netjoin` events if the nick quit in a netsplit
+// or the join is part of a netjoin batch.
+func (client *Client) handleNetsplitJoin(event *Event, channel *Channel) {
+	servers := client.netsplitBatchServers(event, "netjoin")
+	if servers == "" {
+		split, ok := client.netsplit.nicks[strings.ToLower(event.Nick)]
+		if !ok || time.Since(split.time) > netsplitMemory {
+			return
+		}
+
+		servers = split.servers
+	}
+
+	event.Hide()
+	client.addNetsplitNick("netjoin", servers, channel, event.Nick)
+	client.scheduleNetsplitFlush(event)
+}
+
+// netsplitBatchServers gets the servers from the event's batch if it's of the batch type.
+func (client *Client) netsplitBatchServers(event *Event, batchType string) string {
+	ref, ok := event.Tags["batch"]
+	if !ok {
+		return ""
+	}
+
+	client.mutex.RLock()
+	batch := client.batches[ref]
+	client.mutex.RUnlock()
+
+	if batch == nil || batch.Type != batchType || len(batch.Params) < 2 {
+		return ""
+	}
+
+	return batch.Params[0] + " " + batch.Params[1]
+}
+
+func (client *Client) addNetsplitNick(verb, servers string, channel *Channel, nick string) {
+	for _, group := range client.netsplit.pending {
+		if group.verb == verb && group.servers == servers && group.channel == channel {
+			group.nicks = append(group.nicks, nick)
+			return
+		}
+	}
+
+	client.netsplit.pending = append(client.netsplit.pending, &netsplitGroup{
+		verb:    verb,
+		servers: servers,
+		channel: channel,
+		nicks:   []string{nick},
+	})
+}
+
+// scheduleNetsplitFlush emits the groups after netsplitDelay without any more quits or joins. Events in a
+// batch are flushed when the batch ends instead.
+func (client *Client) scheduleNetsplitFlush(event *Event) {
+	if client.netsplit.timer != nil {
+		client.netsplit.timer.Stop()
+		client.netsplit.timer = nil
+	}
+	if _, ok := event.Tags["batch"]; ok {
+		return
+	}
+
+	client.netsplit.timer = time.AfterFunc(netsplitDelay, func() {
+		client.EmitNonBlocking(NewEvent("hook", "netsplit_flush"))
+	})
+}
+
+// flushNetsplits emits an `info.netsplit` or `info.netjoin` per channel with the servers as the first two
+// arguments and the nicks after them.
+func (client *Client) flushNetsplits() {
+	if client.netsplit.timer != nil {
+		client.netsplit.timer.Stop()
+		client.netsplit.timer = nil
+	}
+
+	for _, group := range client.netsplit.pending {
+		servers := strings.SplitN(group.servers, " ", 2)
+
+		event := NewEvent("info", group.verb)
+		event.Args = append(append(event.Args, servers...), group.nicks...)
+		if group.verb == "netsplit" {
+			event.Text = "Netsplit " + servers[0] + " <-> " + servers[1] + ", quits: " + strings.Join(group.nicks, ", ")
+		} else {
+			event.Text = "Netsplit " + servers[0] + " <-> " + servers[1] + " over, joins: " + strings.Join(group.nicks, ", ")
+
+			for _, nick := range group.nicks {
+				delete(client.netsplit.nicks, strings.ToLower(nick))
+			}
+		}
+		event.targets = append(event.targets, group.channel)
+
+		client.EmitNonBlocking(event)
+	}
+	client.netsplit.pending = client.netsplit.pending[:0]
+
+	for nick, split := range client.netsplit.nicks {
+		if time.Since(split.time) > netsplitMemory {
+			delete(client.netsplit.nicks, nick)
+		}
+	}
+}
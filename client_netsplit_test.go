@@ -0,0 +1,95 @@
+package irc_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/internal/irctest"
+)
+
+func TestClient_Netsplit(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+
+	infos := client.Subscribe(context.Background(), irc.EventFilter{Patterns: []string{"info.netsplit", "info.netjoin"}})
+	packets := client.Subscribe(context.Background(), irc.EventFilter{Patterns: []string{"packet.quit", "packet.join"}})
+
+	expectInfo := func(lines ...string) func() error {
+		return func() error {
+			results := make([]string, 0, len(lines))
+			for range lines {
+				select {
+				case event := <-infos:
+					results = append(results, event.Name()+" "+event.ChannelTarget().Name()+" "+strings.Join(event.Args, " "))
+				case <-time.After(time.Second * 3):
+					return fmt.Errorf("timed out waiting for %q", lines)
+				}
+			}
+
+			if strings.Join(results, "\n") != strings.Join(lines, "\n") {
+				return fmt.Errorf("expected %q, got %q", lines, results)
+			}
+
+			return nil
+		}
+	}
+
+	interaction := irctest.Interaction{
+		Strict: true,
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Server: ":Test!~Tester@127.0.0.1 JOIN #A"},
+			irctest.InteractionLine{Server: ":testserver.example.com 353 Test = #A :Test A1 A2 Both"},
+			irctest.InteractionLine{Server: ":testserver.example.com 366 Test #A :End of /NAMES list."},
+			irctest.InteractionLine{Server: ":Test!~Tester@127.0.0.1 JOIN #B"},
+			irctest.InteractionLine{Server: ":testserver.example.com 353 Test = #B :Test B1 Both"},
+			irctest.InteractionLine{Server: ":testserver.example.com 366 Test #B :End of /NAMES list."},
+			irctest.InteractionLine{Server: ":A1!~a1@10.32.0.1 QUIT :hub.example.com leaf.example.com"},
+			irctest.InteractionLine{Server: ":Both!~both@10.32.0.2 QUIT :hub.example.com leaf.example.com"},
+			irctest.InteractionLine{Server: ":B1!~b1@10.32.0.3 QUIT :Quit: example.com example.org"},
+			irctest.InteractionLine{Callback: expectInfo(
+				"info.netsplit #A hub.example.com leaf.example.com A1 Both",
+				"info.netsplit #B hub.example.com leaf.example.com Both",
+			)},
+			irctest.InteractionLine{Server: ":A1!~a1@10.32.0.1 JOIN #A"},
+			irctest.InteractionLine{Server: ":Both!~both@10.32.0.2 JOIN #A"},
+			irctest.InteractionLine{Server: ":Both!~both@10.32.0.2 JOIN #B"},
+			irctest.InteractionLine{Server: ":B1!~b1@10.32.0.3 JOIN #B"},
+			irctest.InteractionLine{Callback: expectInfo(
+				"info.netjoin #A hub.example.com leaf.example.com A1 Both",
+				"info.netjoin #B hub.example.com leaf.example.com Both",
+			)},
+			irctest.InteractionLine{Server: ":testserver.example.com BATCH +split netsplit irc.example.net irc.example.org"},
+			irctest.InteractionLine{Server: "@batch=split :A2!~a2@10.32.0.4 QUIT :*.net *.split"},
+			irctest.InteractionLine{Server: ":testserver.example.com BATCH -split"},
+			irctest.InteractionLine{Callback: expectInfo(
+				"info.netsplit #A irc.example.net irc.example.org A2",
+			)},
+		),
+	}
+	runInteraction(t, client, &interaction)
+
+	expected := []string{
+		"packet.join Test true", "packet.join Test true",
+		"packet.quit A1 false", "packet.quit Both false", "packet.quit B1 true",
+		"packet.join A1 false", "packet.join Both false", "packet.join Both false", "packet.join B1 true",
+		"packet.quit A2 false",
+	}
+	for _, line := range expected {
+		select {
+		case event := <-packets:
+			if result := fmt.Sprintf("%s %s %t", event.Name(), event.Nick, !event.Hidden()); result != line {
+				t.Errorf("Expected %q, got %q", line, result)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for %q", line)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package irc
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// requestPlayback asks ZNC's playback module for the messages since the oldest last seen server
+// time, or since the disconnect if no targets have been seen. All targets are requested so that
+// the ones without any seen messages get their backlog too, and handlePlayback hides the messages
+// that have been seen already. On the first connection, everything ZNC has buffered is requested.
+func (client *Client) requestPlayback() {
+	client.mutex.Lock()
+	client.playbackStart = time.Now()
+	since := client.disconnectTime
+	for _, lastSeen := range client.lastSeen {
+		if since.IsZero() || lastSeen.Before(since) {
+			since = lastSeen
+		}
+	}
+	client.mutex.Unlock()
+
+	if since.IsZero() {
+		client.SendQueued("PRIVMSG *playback :PLAY *")
+	} else {
+		client.SendQueued("PRIVMSG *playback :PLAY * " + formatPlaybackTime(since))
+	}
+}
+
+// handlePlayback keeps track of the last seen server time of every channel and query, and marks
+// messages replayed by ZNC's playback module with the `playback` render tag. Replayed messages that
+// have already been seen are also hidden.
+func (client *Client) handlePlayback(event *Event, serverTime time.Time) {
+	if serverTime.IsZero() || (event.kind != "packet" && event.kind != "ctcp" && event.kind != "ctcp-reply") {
+		return
+	}
+
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	if client.capEnabled["znc.in/playback"] {
+		playback := false
+		if ref, ok := event.Tags["batch"]; ok && client.batches[ref] != nil {
+			playback = client.batches[ref].Type == "znc.in/playback"
+		} else if !client.capEnabled["batch"] {
+			playback = serverTime.Before(client.playbackStart)
+		}
+
+		if playback {
+			event.RenderTags["playback"] = "true"
+		}
+	}
+
+	for _, target := range event.targets {
+		if target.Kind() != "channel" && target.Kind() != "query" {
+			continue
+		}
+
+		key := strings.ToLower(target.Name())
+		if lastSeen, ok := client.lastSeen[key]; ok && !serverTime.After(lastSeen) {
+			if event.RenderTags["playback"] != "" {
+				event.Hide()
+			}
+
+			continue
+		}
+
+		client.lastSeen[key] = serverTime
+	}
+}
+
+func formatPlaybackTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/float64(time.Second), 'f', 3, 64)
+}
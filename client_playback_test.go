@@ -0,0 +1,155 @@
+package irc_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/internal/irctest"
+)
+
+func TestClient_ZNCPlayback(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+
+	messages := client.Subscribe(context.Background(), irc.EventFilter{Patterns: []string{"packet.privmsg"}})
+	receive := func(n int) ([]*irc.Event, error) {
+		events := make([]*irc.Event, 0, n)
+		for len(events) < n {
+			select {
+			case event := <-messages:
+				events = append(events, event)
+			case <-time.After(time.Second):
+				return nil, errors.New("timed out waiting for messages")
+			}
+		}
+
+		return events, nil
+	}
+
+	registration := []irctest.InteractionLine{
+		{Client: "CAP LS 302"},
+		{Client: "NICK Test"},
+		{Client: "USER Tester 8 * :..."},
+		{Server: ":irc.znc.in CAP * LS :batch server-time znc.in/playback znc.in/self-message"},
+		{Client: "CAP REQ :batch server-time znc.in/playback znc.in/self-message"},
+		{Server: ":irc.znc.in CAP * ACK :batch server-time znc.in/playback znc.in/self-message"},
+		{Client: "CAP END"},
+		{Server: ":irc.znc.in 001 Test :Welcome to ZNC"},
+		{Server: ":irc.znc.in 005 Test CHANTYPES=# :are supported by this server"},
+		{Server: ":Test!~Tester@127.0.0.1 JOIN #Test"},
+		{Server: ":Test!~Tester@127.0.0.1 JOIN #Quiet"},
+		{Server: ":irc.znc.in 376 Test :End of /MOTD command."},
+	}
+
+	interaction := irctest.Interaction{
+		Lines: append(registration[:len(registration):len(registration)],
+			irctest.InteractionLine{Client: "PRIVMSG *playback :PLAY *"},
+			irctest.InteractionLine{Server: ":irc.znc.in BATCH +p znc.in/playback #Test"},
+			irctest.InteractionLine{Server: "@batch=p;time=2020-01-01T00:00:00.000Z :Gisle!~irce@10.32.0.1 PRIVMSG #Test :Old message"},
+			irctest.InteractionLine{Server: ":irc.znc.in BATCH -p"},
+			irctest.InteractionLine{Server: "@time=2020-01-01T00:01:00.000Z :Gisle!~irce@10.32.0.1 PRIVMSG #Test :New message"},
+			irctest.InteractionLine{Server: "@time=2020-01-01T00:01:30.000Z :Test!~Tester@127.0.0.1 PRIVMSG #Test :Sent to the channel"},
+			irctest.InteractionLine{Server: "@time=2020-01-01T00:02:00.000Z :Test!~Tester@127.0.0.1 PRIVMSG Friend :Sent from another client"},
+			irctest.InteractionLine{Server: ":Test!~Tester@127.0.0.1 JOIN #Old"},
+			irctest.InteractionLine{Server: "@time=2020-01-01T00:00:30.000Z :Gisle!~irce@10.32.0.1 PRIVMSG #Old :Parted after this"},
+			irctest.InteractionLine{Server: ":Test!~Tester@127.0.0.1 PART #Old"},
+			irctest.InteractionLine{Server: "PING :irc.znc.in"},
+			irctest.InteractionLine{Client: "PONG :irc.znc.in"},
+			irctest.InteractionLine{Callback: func() error {
+				events, err := receive(5)
+				if err != nil {
+					return err
+				}
+
+				oldMessage := events[0]
+				if oldMessage.RenderTags["playback"] != "true" {
+					return errors.New("old message should be marked as playback")
+				}
+
+				channelMessage := events[2]
+				if channelMessage.ChannelTarget() == nil || channelMessage.QueryTarget() != nil {
+					return errors.New("self-message to the channel should be in the channel")
+				}
+				if client.Query("#Test") != nil {
+					return errors.New("self-message to the channel should not open a query")
+				}
+
+				newMessage := events[3]
+				if newMessage.RenderTags["playback"] != "" {
+					return errors.New("self-message should not be marked as playback")
+				}
+				if newMessage.QueryTarget() == nil || newMessage.QueryTarget().Name() != "Friend" {
+					return errors.New("self-message should be in the query with Friend")
+				}
+
+				if client.Query("Friend") == nil {
+					return errors.New("query with Friend should exist")
+				}
+
+				return nil
+			}},
+		),
+	}
+	runInteraction(t, client, &interaction)
+
+	playAll := false
+	for _, line := range interaction.Log {
+		if strings.HasPrefix(line, "PRIVMSG *playback :") {
+			playAll = line == "PRIVMSG *playback :PLAY *"
+		}
+	}
+	if !playAll {
+		t.Error("Everything should be played back on the first connection")
+	}
+
+	// The oldest last seen time is in #Test, since #Old has been parted. #Quiet has not been seen, but
+	// it must still get its backlog.
+	interaction2 := irctest.Interaction{
+		Lines: append(registration[:len(registration):len(registration)],
+			irctest.InteractionLine{Client: "PRIVMSG *playback :PLAY * 1577836890.000"},
+			irctest.InteractionLine{Server: ":irc.znc.in BATCH +p znc.in/playback #Test"},
+			irctest.InteractionLine{Server: "@batch=p;time=2020-01-01T00:01:00.000Z :Gisle!~irce@10.32.0.1 PRIVMSG #Test :New message"},
+			irctest.InteractionLine{Server: ":irc.znc.in BATCH -p"},
+			irctest.InteractionLine{Server: ":irc.znc.in BATCH +q znc.in/playback #Quiet"},
+			irctest.InteractionLine{Server: "@batch=q;time=2020-01-01T00:01:45.000Z :Gisle!~irce@10.32.0.1 PRIVMSG #Quiet :Missed message"},
+			irctest.InteractionLine{Server: ":irc.znc.in BATCH -q"},
+			irctest.InteractionLine{Server: "PING :irc.znc.in"},
+			irctest.InteractionLine{Client: "PONG :irc.znc.in"},
+			irctest.InteractionLine{Callback: func() error {
+				events, err := receive(2)
+				if err != nil {
+					return err
+				}
+
+				if duplicate := events[0]; !duplicate.Hidden() {
+					return errors.New("duplicate message should be hidden")
+				}
+
+				missed := events[1]
+				if missed.Hidden() || missed.RenderTags["playback"] != "true" {
+					return errors.New("message in the quiet channel should be shown as playback")
+				}
+				if missed.ChannelTarget() == nil || missed.ChannelTarget().Name() != "#Quiet" {
+					return errors.New("message in the quiet channel should be in #Quiet")
+				}
+
+				return nil
+			}},
+		),
+	}
+	runInteraction(t, client, &interaction2)
+
+	for _, line := range interaction2.Log {
+		if strings.HasPrefix(line, "PRIVMSG *playback :") && line != "PRIVMSG *playback :PLAY * 1577836890.000" {
+			t.Error("Unexpected playback request:", line)
+		}
+	}
+}
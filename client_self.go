@@ -0,0 +1,139 @@
+package irc
+
+import (
+	"sort"
+	"strings"
+)
+
+// Modes gets the client's user modes, like `iwx`, without a `+`.
+func (client *Client) Modes() string {
+	client.mutex.RLock()
+	defer client.mutex.RUnlock()
+
+	return client.modes
+}
+
+// HasMode returns true if the client has the user mode.
+func (client *Client) HasMode(mode rune) bool {
+	return strings.ContainsRune(client.Modes(), mode)
+}
+
+// Account gets the account the client is logged in to, or an empty string if it's not.
+func (client *Client) Account() string {
+	client.mutex.RLock()
+	defer client.mutex.RUnlock()
+
+	return client.account
+}
+
+// Away returns true if the server has marked the client as away.
+func (client *Client) Away() bool {
+	client.mutex.RLock()
+	defer client.mutex.RUnlock()
+
+	return client.away
+}
+
+// setNick changes the client's nick, and emits `self.nick` with the old and new nick as arguments.
+func (client *Client) setNick(nick string) {
+	client.mutex.Lock()
+	oldNick := client.nick
+	client.nick = nick
+	client.mutex.Unlock()
+
+	if oldNick != nick {
+		client.emitSelf("nick", oldNick, nick)
+	}
+}
+
+// setUserHost changes the client's user and host, and emits `self.host` with them as arguments.
+// An empty user is left unchanged.
+func (client *Client) setUserHost(user, host string) {
+	client.mutex.Lock()
+	changed := (user != "" && user != client.user) || host != client.host
+	if user != "" {
+		client.user = user
+	}
+	client.host = host
+	user = client.user
+	client.mutex.Unlock()
+
+	if changed {
+		client.emitSelf("host", user, host)
+	}
+}
+
+// setAccount changes the client's account, and emits `self.account` with the account as the argument.
+// It's empty when logged out.
+func (client *Client) setAccount(account string) {
+	if account == "*" {
+		account = ""
+	}
+
+	client.mutex.Lock()
+	changed := client.account != account
+	client.account = account
+	client.mutex.Unlock()
+
+	if changed {
+		client.emitSelf("account", account)
+	}
+}
+
+// setAway changes the client's away state, and emits `self.away` or `self.back`.
+func (client *Client) setAway(away bool) {
+	client.mutex.Lock()
+	changed := client.away != away
+	client.away = away
+	client.mutex.Unlock()
+
+	if changed && away {
+		client.emitSelf("away")
+	} else if changed {
+		client.emitSelf("back")
+	}
+}
+
+// applyUserModes applies a mode change like `+iw-x` to the client's user modes, and emits
+// `self.modes` with the new modes and the change as arguments. If reset is true, the modes
+// are replaced, like with the 221 numeric.
+func (client *Client) applyUserModes(change string, reset bool) {
+	client.mutex.Lock()
+	oldModes := client.modes
+	modes := []byte(client.modes)
+	if reset {
+		modes = modes[:0]
+	}
+
+	plus := true
+	for i := 0; i < len(change); i++ {
+		switch ch := change[i]; ch {
+		case '+':
+			plus = true
+		case '-':
+			plus = false
+		default:
+			index := strings.IndexByte(string(modes), ch)
+			if plus && index == -1 {
+				modes = append(modes, ch)
+			} else if !plus && index != -1 {
+				modes = append(modes[:index], modes[index+1:]...)
+			}
+		}
+	}
+
+	sort.Slice(modes, func(i, j int) bool { return modes[i] < modes[j] })
+	client.modes = string(modes)
+	newModes := client.modes
+	client.mutex.Unlock()
+
+	if newModes != oldModes {
+		client.emitSelf("modes", newModes, change)
+	}
+}
+
+func (client *Client) emitSelf(verb string, args ...string) {
+	event := NewEvent("self", verb)
+	event.Args = append(event.Args, args...)
+	client.EmitNonBlocking(event)
+}
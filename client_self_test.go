@@ -0,0 +1,95 @@
+package irc_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/internal/irctest"
+)
+
+func TestClient_Self(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+
+	events := client.Subscribe(context.Background(), irc.EventFilter{Patterns: []string{"self.*"}})
+
+	interaction := irctest.Interaction{
+		Strict: true,
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Server: ":Test!~Tester@testclient.example.com NICK Renamed"},
+			irctest.InteractionLine{Server: ":testserver.example.com 221 Renamed +wi"},
+			irctest.InteractionLine{Server: ":Renamed MODE Renamed :+x-w"},
+			irctest.InteractionLine{Server: ":testserver.example.com 900 Renamed Renamed!~Tester@testclient.example.com Account :You are now logged in as Account"},
+			irctest.InteractionLine{Server: ":testserver.example.com 306 Renamed :You have been marked as being away"},
+			irctest.InteractionLine{Server: ":testserver.example.com 396 Renamed cloaked.example.com :is now your displayed host"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+			irctest.InteractionLine{Callback: func() error {
+				state := client.State()
+				if client.Nick() != "Renamed" || state.Nick != "Renamed" {
+					return fmt.Errorf("nick should be Renamed, not %s", client.Nick())
+				}
+				if client.Modes() != "ix" || state.Modes != "ix" || !client.HasMode('x') {
+					return fmt.Errorf("modes should be ix, not %s", client.Modes())
+				}
+				if client.Account() != "Account" || state.Account != "Account" {
+					return fmt.Errorf("account should be Account, not %s", client.Account())
+				}
+				if !client.Away() || !state.Away {
+					return fmt.Errorf("client should be away")
+				}
+				if client.Host() != "cloaked.example.com" || client.User() != "~Tester" {
+					return fmt.Errorf("host should be ~Tester@cloaked.example.com, not %s@%s", client.User(), client.Host())
+				}
+
+				return nil
+			}},
+			irctest.InteractionLine{Server: ":testserver.example.com 901 Renamed Renamed!~Tester@cloaked.example.com :You are now logged out"},
+			irctest.InteractionLine{Server: ":testserver.example.com 305 Renamed :You are no longer marked as being away"},
+			irctest.InteractionLine{Server: ":Renamed!~Tester@cloaked.example.com PRIVMSG Renamed :Hello"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+			irctest.InteractionLine{Callback: func() error {
+				if client.Account() != "" || client.Away() {
+					return fmt.Errorf("client should be logged out and back")
+				}
+				if client.Query("Renamed") == nil {
+					return fmt.Errorf("message to self after the nick change should open a query")
+				}
+
+				return nil
+			}},
+		),
+	}
+	runInteraction(t, client, &interaction)
+
+	expected := []string{
+		"self.host ~Tester testclient.example.com",
+		"self.nick Test Renamed",
+		"self.modes iw +wi",
+		"self.modes ix +x-w",
+		"self.account Account",
+		"self.away",
+		"self.host ~Tester cloaked.example.com",
+		"self.account",
+		"self.back",
+	}
+	for _, line := range expected {
+		select {
+		case event := <-events:
+			if result := strings.TrimSpace(event.Name() + " " + strings.Join(event.Args, " ")); result != line {
+				t.Errorf("Expected %q, got %q", line, result)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for %q", line)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package irc
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// An OverflowPolicy decides what a subscription does when its buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDrop drops events that do not fit in the buffer.
+	OverflowDrop OverflowPolicy = iota
+	// OverflowBlock blocks the event loop until there is room in the buffer.
+	OverflowBlock
+)
+
+// subscriptionPriority makes the subscriptions run after the handlers with the default priority, so
+// that they get any changes made by those handlers.
+const subscriptionPriority = -1000
+
+// EventFilter is used by Client.Subscribe to decide which events to deliver, and how.
+type EventFilter struct {
+	// Patterns limits the events to the ones matching any of the patterns. See Client.On
+	// for the patterns. If it's empty, all events will be delivered.
+	Patterns []string
+
+	// Buffer is the size of the channel's buffer. The default is 64.
+	Buffer int
+
+	// Overflow is what happens when the buffer is full. The default is to drop events.
+	Overflow OverflowPolicy
+}
+
+// Subscribe delivers copies of the events matching the filter on a channel, which is safe to read
+// outside the event loop. The channel is closed when either the context or the client is done.
+func (client *Client) Subscribe(ctx context.Context, filter EventFilter) <-chan *Event {
+	buffer := filter.Buffer
+	if buffer <= 0 {
+		buffer = 64
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	events := make(chan *Event, buffer)
+	mutex := sync.Mutex{}
+	closed := false
+
+	handler := func(event *Event, _ *Client) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if closed {
+			return
+		}
+
+		if filter.Overflow == OverflowBlock {
+			select {
+			case events <- event.Copy():
+			case <-ctx.Done():
+			case <-client.ctx.Done():
+			}
+		} else {
+			select {
+			case events <- event.Copy():
+			default:
+			}
+		}
+	}
+
+	patterns := subscriptionPatterns(filter.Patterns)
+	handles := make([]HandlerHandle, 0, len(patterns))
+	for _, pattern := range patterns {
+		handles = append(handles, client.OnPriority(pattern, subscriptionPriority, handler))
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-client.ctx.Done():
+		}
+		cancel()
+
+		for _, handle := range handles {
+			handle.Remove()
+		}
+
+		mutex.Lock()
+		closed = true
+		close(events)
+		mutex.Unlock()
+	}()
+
+	return events
+}
+
+// subscriptionPatterns removes the patterns that are covered by other patterns, so that no event
+// is delivered twice.
+func subscriptionPatterns(patterns []string) []string {
+	kinds := make(map[string]bool, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*" {
+			return []string{"*"}
+		}
+
+		if strings.HasSuffix(pattern, ".*") {
+			kinds[strings.TrimSuffix(pattern, ".*")] = true
+		}
+	}
+	if len(patterns) == 0 {
+		return []string{"*"}
+	}
+
+	result := make([]string, 0, len(patterns))
+	seen := make(map[string]bool, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if seen[pattern] {
+			continue
+		}
+		seen[pattern] = true
+
+		if !strings.HasSuffix(pattern, ".*") {
+			if dot := strings.IndexByte(pattern, '.'); dot != -1 && kinds[pattern[:dot]] {
+				continue
+			}
+		}
+
+		result = append(result, pattern)
+	}
+
+	return result
+}
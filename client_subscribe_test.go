@@ -0,0 +1,98 @@
+package irc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gissleh/irc"
+)
+
+func TestClient_Subscribe(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{})
+
+	emit := func(kind, verb, text string) {
+		event := irc.NewEvent(kind, verb)
+		event.Text = text
+		if err := client.EmitSync(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Drop", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		events := client.Subscribe(ctx, irc.EventFilter{
+			Patterns: []string{"test.*", "test.foo"},
+			Buffer:   2,
+		})
+
+		emit("test", "foo", "1")
+		emit("other", "foo", "ignored")
+		emit("test", "bar", "2")
+		emit("test", "foo", "dropped")
+
+		for _, expected := range []string{"1", "2"} {
+			event := <-events
+			if event.Text != expected {
+				t.Errorf("Expected %#+v, got %#+v", expected, event.Text)
+			}
+		}
+
+		cancel()
+		select {
+		case event, ok := <-events:
+			if ok {
+				t.Errorf("Expected closed channel, got %#+v", event)
+			}
+		case <-time.After(time.Second):
+			t.Error("Channel should be closed when the context is done")
+		}
+	})
+
+	t.Run("Block", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events := client.Subscribe(ctx, irc.EventFilter{
+			Patterns: []string{"test.foo"},
+			Buffer:   1,
+			Overflow: irc.OverflowBlock,
+		})
+
+		go func() {
+			for _, text := range []string{"1", "2", "3"} {
+				emit("test", "foo", text)
+			}
+		}()
+
+		for _, expected := range []string{"1", "2", "3"} {
+			select {
+			case event := <-events:
+				if event.Text != expected {
+					t.Errorf("Expected %#+v, got %#+v", expected, event.Text)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Timed out waiting for", expected)
+			}
+		}
+	})
+
+	t.Run("ClientDone", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		client := irc.New(ctx, irc.Config{})
+		events := client.Subscribe(context.Background(), irc.EventFilter{})
+
+		cancel()
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case _, ok := <-events:
+				if !ok {
+					return
+				}
+			case <-timeout:
+				t.Fatal("Channel should be closed when the client is done")
+			}
+		}
+	})
+}
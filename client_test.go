@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/gissleh/irc/handlers"
 	"testing"
+	"time"
 
 	"github.com/gissleh/irc"
 	"github.com/gissleh/irc/internal/irctest"
@@ -210,6 +211,9 @@ func TestClient(t *testing.T) {
 				if query == nil {
 					return errors.New("Did not find query")
 				}
+				if query.ID() == client.ID() || client.TargetByID(query.ID()) != query {
+					return errors.New("query should have its own ID")
+				}
 
 				event := logger.Last("packet", "PRIVMSG")
 				if event == nil {
@@ -473,3 +477,158 @@ func TestParenthesesBug(t *testing.T) {
 		t.Error("Message was not received")
 	}
 }
+
+// runInteraction connects the client to the interaction and reports any failures once it's done.
+func runInteraction(t *testing.T, client *irc.Client, interaction *irctest.Interaction) {
+	addr, err := interaction.Listen()
+	if err != nil {
+		t.Fatal("Listen:", err)
+	}
+
+	err = client.Connect(addr, false)
+	if err != nil {
+		t.Fatal("Connect:", err)
+	}
+
+	interaction.Wait()
+
+	fail := interaction.Failure
+	if fail != nil {
+		t.Error("Index:", fail.Index)
+		t.Error("NetErr:", fail.NetErr)
+		t.Error("CBErr:", fail.CBErr)
+		t.Error("Result:", fail.Result)
+		if fail.Index >= 0 {
+			if interaction.Lines[fail.Index].Server != "" {
+				t.Error("Line.Server:", interaction.Lines[fail.Index].Server)
+			}
+			if interaction.Lines[fail.Index].Client != "" {
+				t.Error("Line.Client:", interaction.Lines[fail.Index].Client)
+			}
+		}
+
+		for i, logLine := range interaction.Log {
+			t.Logf("Log[%d] = %#+v", i, logLine)
+		}
+	}
+}
+
+func TestClient_SayMany(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+
+	interaction := irctest.Interaction{
+		Strict: true,
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Callback: func() error {
+				client.SayMany([]string{"#a", "#b", "#c", "#d", "#e", "Gisle"}, "Hello")
+				return nil
+			}},
+			irctest.InteractionLine{Client: "PRIVMSG #a,#b,#c,#d :Hello"},
+			irctest.InteractionLine{Client: "PRIVMSG #e,Gisle :Hello"},
+			irctest.InteractionLine{Server: ":testserver.example.com 005 Test TARGMAX=PRIVMSG:4 :are supported by this server"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+			irctest.InteractionLine{Callback: func() error {
+				client.NoticeMany([]string{"#a", "#b"}, "Hello")
+				return nil
+			}},
+			irctest.InteractionLine{Client: "NOTICE #a :Hello"},
+			irctest.InteractionLine{Client: "NOTICE #b :Hello"},
+		),
+	}
+	runInteraction(t, client, &interaction)
+}
+
+func TestClient_StatusMsg(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+
+	events := client.Subscribe(context.Background(), irc.EventFilter{Patterns: []string{"packet.privmsg", "packet.notice"}})
+
+	interaction := irctest.Interaction{
+		Strict: true,
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Server: ":Test!~Tester@127.0.0.1 JOIN #Test"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 PRIVMSG @#Test :Ops only"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 NOTICE +#Test :Voiced and ops"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 PRIVMSG #Test :Everyone"},
+			irctest.InteractionLine{Callback: func() error {
+				client.SayStatus("@", "#Test", "Hello ops")
+				return nil
+			}},
+			irctest.InteractionLine{Client: "PRIVMSG @#Test :Hello ops"},
+		),
+	}
+	runInteraction(t, client, &interaction)
+
+	for _, prefix := range []string{"@", "+", ""} {
+		select {
+		case event := <-events:
+			if channel := event.ChannelTarget(); channel == nil || channel.Name() != "#Test" {
+				t.Errorf("%q should be in #Test", event.Text)
+			}
+			if event.RenderTags["statusPrefix"] != prefix {
+				t.Errorf("%q should have status prefix %q, not %q", event.Text, prefix, event.RenderTags["statusPrefix"])
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for messages")
+		}
+	}
+}
+
+func TestClient_SASLEnd(t *testing.T) {
+	table := []struct {
+		Reply  string
+		Cancel bool
+	}{
+		{":irc.example.com 902 * :You must use a nick assigned to you", true},
+		{":irc.example.com 904 * :SASL authentication failed", true},
+		{":irc.example.com 905 * :SASL message too long", true},
+		{":irc.example.com 903 * :SASL authentication successful", false},
+		{":irc.example.com 907 * :You have already authenticated using SASL", false},
+	}
+
+	for _, row := range table {
+		t.Run(row.Reply[17:20], func(t *testing.T) {
+			client := irc.New(context.Background(), irc.Config{
+				Nick:     "Test",
+				User:     "Tester",
+				RealName: "...",
+				SendRate: 1000,
+				SASL: &irc.SASLConfig{
+					AuthenticationIdentity: "Test",
+					Password:               "hunter2",
+				},
+			})
+
+			lines := []irctest.InteractionLine{
+				{Client: "CAP LS 302"},
+				{Client: "NICK Test"},
+				{Client: "USER Tester 8 * :..."},
+				{Server: ":irc.example.com CAP * LS :sasl=PLAIN"},
+				{Client: "CAP REQ :sasl"},
+				{Server: ":irc.example.com CAP * ACK :sasl"},
+				{Client: "AUTHENTICATE PLAIN"},
+				{Server: "AUTHENTICATE +"},
+				{Client: "AUTHENTICATE VGVzdAAAaHVudGVyMg=="},
+				{Server: row.Reply},
+			}
+			if row.Cancel {
+				lines = append(lines, irctest.InteractionLine{Client: "AUTHENTICATE *"})
+			}
+			lines = append(lines, irctest.InteractionLine{Client: "CAP END"})
+
+			interaction := irctest.Interaction{Strict: true, Lines: lines}
+			runInteraction(t, client, &interaction)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package irc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoSuchNick is returned by Client.Whois if the server replies that the nick does not exist.
+var ErrNoSuchNick = errors.New("irc: no such nick")
+
+// ErrNoSuchServer is returned by Client.Whois if the server replies that the server does not exist. Since
+// the WHOIS is sent to the nick's own server, some servers reply with this if the nick is not online.
+var ErrNoSuchServer = errors.New("irc: no such server")
+
+// WhoisResult is the collected result of a WHOIS query.
+type WhoisResult struct {
+	Nick            string         `json:"nick"`
+	User            string         `json:"user"`
+	Host            string         `json:"host"`
+	RealName        string         `json:"realName"`
+	Server          string         `json:"server,omitempty"`
+	ServerInfo      string         `json:"serverInfo,omitempty"`
+	Operator        bool           `json:"operator,omitempty"`
+	Channels        []WhoisChannel `json:"channels,omitempty"`
+	Account         string         `json:"account,omitempty"`
+	Idle            time.Duration  `json:"idle,omitempty"`
+	SignOn          time.Time      `json:"signOn,omitempty"`
+	Secure          bool           `json:"secure,omitempty"`
+	Away            string         `json:"away,omitempty"`
+	ActualHost      string         `json:"actualHost,omitempty"`
+	ActualIP        string         `json:"actualIp,omitempty"`
+	CertFingerprint string         `json:"certFingerprint,omitempty"`
+}
+
+// WhoisChannel is a channel listed in the WHOIS reply, along with the user's prefixes in it.
+type WhoisChannel struct {
+	Name     string `json:"name"`
+	Prefixes string `json:"prefixes,omitempty"`
+}
+
+type whoisRequest struct {
+	result     WhoisResult
+	err        error
+	done       chan struct{}
+	started    bool
+	noSuchNick bool
+}
+
+// Whois sends a WHOIS for the nick to the nick's own server, which includes the idle time, and
+// collects the replies until the end of the list. Concurrent calls for the same nick will share the
+// same request. It returns ErrNoSuchNick or ErrNoSuchServer if the server replies with those errors,
+// and ErrNoConnection if the connection is lost before the reply is finished.
+func (client *Client) Whois(ctx context.Context, nick string) (WhoisResult, error) {
+	if !client.Connected() {
+		return WhoisResult{}, ErrNoConnection
+	}
+
+	key := strings.ToLower(nick)
+
+	client.mutex.Lock()
+	request := client.whoisRequests[key]
+	if request == nil {
+		request = &whoisRequest{
+			result: WhoisResult{Nick: nick},
+			done:   make(chan struct{}),
+		}
+		client.whoisRequests[key] = request
+		client.mutex.Unlock()
+
+		client.SendQueuedf("WHOIS %s %s", nick, nick)
+	} else {
+		client.mutex.Unlock()
+	}
+
+	select {
+	case <-request.done:
+		return request.result, request.err
+	case <-ctx.Done():
+		return WhoisResult{}, ctx.Err()
+	case <-client.ctx.Done():
+		return WhoisResult{}, ErrDestroyed
+	}
+}
+
+// handleWhois handles the WHOIS replies for the client's pending requests.
+func (client *Client) handleWhois(event *Event) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	if len(client.whoisRequests) == 0 {
+		return
+	}
+
+	request := client.whoisRequests[strings.ToLower(event.Arg(1))]
+	if request == nil {
+		return
+	}
+	result := &request.result
+
+	switch event.verb {
+	case "311": // <client> <nick> <user> <host> * :<realname>
+		request.started = true
+		request.noSuchNick = false
+		result.Nick = event.Arg(1)
+		result.User = event.Arg(2)
+		result.Host = event.Arg(3)
+		result.RealName = event.Text
+	case "312": // <client> <nick> <server> :<server info>
+		result.Server = event.Arg(2)
+		result.ServerInfo = event.Text
+	case "313": // <client> <nick> :is an IRC operator
+		result.Operator = true
+	case "317": // <client> <nick> <secs> <signon> :seconds idle, signon time
+		if idle, err := strconv.ParseInt(event.Arg(2), 10, 64); err == nil {
+			result.Idle = time.Duration(idle) * time.Second
+		}
+		if len(event.Args) > 3 {
+			if signOn, err := strconv.ParseInt(event.Args[3], 10, 64); err == nil {
+				result.SignOn = time.Unix(signOn, 0)
+			}
+		}
+	case "319": // <client> <nick> :[prefix]<channel>{ [prefix]<channel>}
+		for _, token := range strings.Fields(event.Text) {
+			name, _, prefixes := client.isupport.ParsePrefixedNick(token)
+			result.Channels = append(result.Channels, WhoisChannel{Name: name, Prefixes: prefixes})
+		}
+	case "330": // <client> <nick> <account> :is logged in as
+		result.Account = event.Arg(2)
+	case "338": // <client> <nick> [<user@host>] <ip> :Actual user@host, Actual IP
+		if len(event.Args) > 3 {
+			result.ActualHost = event.Args[2]
+			result.ActualIP = event.Args[3]
+		} else {
+			result.ActualIP = event.Arg(2)
+		}
+	case "671": // <client> <nick> :is using a secure connection
+		result.Secure = true
+	case "276": // <client> <nick> :has client certificate fingerprint <fingerprint>
+		if index := strings.LastIndex(event.Text, " "); index != -1 {
+			result.CertFingerprint = event.Text[index+1:]
+		} else {
+			result.CertFingerprint = event.Text
+		}
+	case "301": // <client> <nick> :<message>
+		result.Away = event.Text
+	case "401": // <client> <nick> :No such nick/channel
+		// The 401 could also be for something else sent to the nick, so it's only an error if it
+		// comes before the replies, and it's up to the 318 to end the request.
+		if !request.started {
+			request.noSuchNick = true
+		}
+	case "402": // <client> <server> :No such server, where the server is the nick
+		client.finishWhois(event.Arg(1), ErrNoSuchServer)
+	case "318": // <client> <nick> :End of /WHOIS list
+		if request.noSuchNick {
+			client.finishWhois(event.Arg(1), ErrNoSuchNick)
+		} else {
+			client.finishWhois(event.Arg(1), nil)
+		}
+	}
+}
+
+// finishWhois resolves a pending whois request. The client mutex must be held when calling it.
+func (client *Client) finishWhois(nick string, err error) {
+	key := strings.ToLower(nick)
+
+	request := client.whoisRequests[key]
+	if request == nil {
+		return
+	}
+
+	if err != nil {
+		request.result = WhoisResult{}
+		request.err = err
+	}
+
+	delete(client.whoisRequests, key)
+	close(request.done)
+}
@@ -0,0 +1,132 @@
+package irc_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/internal/irctest"
+)
+
+func TestClient_Whois(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+
+	type whoisResult struct {
+		result irc.WhoisResult
+		err    error
+	}
+	results := make(chan whoisResult, 1)
+	whois := func(nick string) func() error {
+		return func() error {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+				defer cancel()
+
+				result, err := client.Whois(ctx, nick)
+				results <- whoisResult{result, err}
+			}()
+
+			return nil
+		}
+	}
+
+	lines := irctest.Registration("Test", "Tester", "...")
+	lines = append(lines,
+		irctest.InteractionLine{Callback: whois("gisle")},
+		irctest.InteractionLine{Client: "WHOIS gisle gisle"},
+		irctest.InteractionLine{Server: ":testserver.example.com 311 Test Gisle ~irce 10.32.0.1 * :Gisle Aune"},
+		irctest.InteractionLine{Server: ":testserver.example.com 319 Test Gisle :@#Test +#Test2 #Test3"},
+		irctest.InteractionLine{Server: ":testserver.example.com 312 Test Gisle testserver.example.com :Test Server"},
+		irctest.InteractionLine{Server: ":testserver.example.com 313 Test Gisle :is an IRC operator"},
+		irctest.InteractionLine{Server: ":testserver.example.com 301 Test Gisle :Doing stuff"},
+		irctest.InteractionLine{Server: ":testserver.example.com 671 Test Gisle :is using a secure connection"},
+		irctest.InteractionLine{Server: ":testserver.example.com 338 Test Gisle ~irce@10.32.0.1 10.32.0.1 :actually using host"},
+		irctest.InteractionLine{Server: ":testserver.example.com 317 Test Gisle 42 1600000000 :seconds idle, signon time"},
+		irctest.InteractionLine{Server: ":testserver.example.com 330 Test Gisle GisleAccount :is logged in as"},
+		irctest.InteractionLine{Server: ":testserver.example.com 318 Test Gisle :End of /WHOIS list."},
+		irctest.InteractionLine{Callback: func() error {
+			res := <-results
+			if res.err != nil {
+				return res.err
+			}
+
+			expected := irc.WhoisResult{
+				Nick:     "Gisle",
+				User:     "~irce",
+				Host:     "10.32.0.1",
+				RealName: "Gisle Aune",
+				Server:   "testserver.example.com", ServerInfo: "Test Server",
+				Operator: true,
+				Channels: []irc.WhoisChannel{
+					{Name: "#Test", Prefixes: "@"},
+					{Name: "#Test2", Prefixes: "+"},
+					{Name: "#Test3"},
+				},
+				Account:    "GisleAccount",
+				Idle:       time.Second * 42,
+				SignOn:     time.Unix(1600000000, 0),
+				Secure:     true,
+				Away:       "Doing stuff",
+				ActualHost: "~irce@10.32.0.1",
+				ActualIP:   "10.32.0.1",
+			}
+			if !reflect.DeepEqual(res.result, expected) {
+				t.Logf("Expected: %#+v", expected)
+				t.Logf("Result:   %#+v", res.result)
+				return errors.New("whois result does not match")
+			}
+
+			return nil
+		}},
+		irctest.InteractionLine{Callback: whois("Nobody")},
+		irctest.InteractionLine{Client: "WHOIS Nobody Nobody"},
+		irctest.InteractionLine{Server: ":testserver.example.com 401 Test Nobody :No such nick/channel"},
+		irctest.InteractionLine{Server: ":testserver.example.com 318 Test Nobody :End of /WHOIS list."},
+		irctest.InteractionLine{Callback: func() error {
+			res := <-results
+			if res.err != irc.ErrNoSuchNick {
+				return errors.New("expected ErrNoSuchNick")
+			}
+
+			return nil
+		}},
+		irctest.InteractionLine{Callback: whois("Busy")},
+		irctest.InteractionLine{Client: "WHOIS Busy Busy"},
+		irctest.InteractionLine{Server: ":testserver.example.com 401 Test Busy :No such nick/channel"},
+		irctest.InteractionLine{Server: ":testserver.example.com 311 Test Busy ~busy 10.32.0.2 * :Busy Bee"},
+		irctest.InteractionLine{Server: ":testserver.example.com 401 Test Busy :No such nick/channel"},
+		irctest.InteractionLine{Server: ":testserver.example.com 318 Test Busy :End of /WHOIS list."},
+		irctest.InteractionLine{Callback: func() error {
+			res := <-results
+			if res.err != nil {
+				return errors.New("a 401 for something else sent to the nick should not fail the whois")
+			}
+			if res.result.RealName != "Busy Bee" {
+				return errors.New("whois result should be collected")
+			}
+
+			return nil
+		}},
+		irctest.InteractionLine{Callback: whois("Gone")},
+		irctest.InteractionLine{Client: "WHOIS Gone Gone"},
+		irctest.InteractionLine{Server: ":testserver.example.com 402 Test Gone :No such server"},
+		irctest.InteractionLine{Callback: func() error {
+			res := <-results
+			if res.err != irc.ErrNoSuchServer {
+				return errors.New("expected ErrNoSuchServer")
+			}
+
+			return nil
+		}},
+	)
+
+	runInteraction(t, client, &irctest.Interaction{Lines: lines})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gissleh/irc"
+	"github.com/gorilla/websocket"
+)
+
+// A message is sent both ways over the websocket. The server sends `state` messages with a
+// client's state upon connecting, followed by `event` messages. The web client sends `input`
+// messages, which are handled like lines typed into the target.
+type message struct {
+	Type     string           `json:"type"`
+	ClientID string           `json:"clientId"`
+	TargetID string           `json:"targetId,omitempty"`
+	Text     string           `json:"text,omitempty"`
+	State    *irc.ClientState `json:"state,omitempty"`
+	Event    *irc.Event       `json:"event,omitempty"`
+}
+
+type gateway struct {
+	manager  *irc.Manager
+	upgrader websocket.Upgrader
+}
+
+func newGateway(manager *irc.Manager) *gateway {
+	return &gateway{manager: manager}
+}
+
+func (gateway *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := gateway.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Subscribe before taking the snapshots so that no events fall between them.
+	clients := gateway.manager.Clients()
+	messages := make(chan message, 64)
+	for _, client := range clients {
+		events := client.Subscribe(ctx, irc.EventFilter{
+			Patterns: []string{"*"},
+			Buffer:   256,
+		})
+
+		go forwardEvents(ctx, client.ID(), events, messages)
+	}
+
+	for _, client := range clients {
+		state := client.State()
+		if err := conn.WriteJSON(message{Type: "state", ClientID: client.ID(), State: &state}); err != nil {
+			return
+		}
+	}
+
+	go func() {
+		defer cancel()
+
+		for {
+			select {
+			case msg := <-messages:
+				if err := conn.WriteJSON(msg); err != nil {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		msg := message{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		switch msg.Type {
+		case "input":
+			client := gateway.manager.Client(msg.ClientID)
+			if client == nil {
+				log.Println("Input for unknown client", msg.ClientID)
+				continue
+			}
+
+			var target irc.Target
+			if msg.TargetID != "" {
+				target = client.TargetByID(msg.TargetID)
+			}
+
+			client.EmitInput(msg.Text, target)
+		}
+	}
+}
+
+// forwardedEvent returns false for the events that are internal to the client, which are the hooks,
+// the input that is handled by the gateway itself, and the ticks.
+func forwardedEvent(event *irc.Event) bool {
+	switch event.Kind() {
+	case "hook", "input":
+		return false
+	}
+
+	return event.Name() != "client.tick"
+}
+
+// forwardEvents sends the client's events as messages until the subscription ends. The events that
+// forwardedEvent rejects are skipped since the web clients cannot use them for anything.
+func forwardEvents(ctx context.Context, clientID string, events <-chan *irc.Event, messages chan<- message) {
+	for event := range events {
+		if !forwardedEvent(event) {
+			continue
+		}
+
+		select {
+		case messages <- message{Type: "event", ClientID: clientID, Event: event}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
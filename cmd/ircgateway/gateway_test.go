@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/handlers"
+	"github.com/gissleh/irc/internal/irctest"
+	"github.com/gorilla/websocket"
+)
+
+func TestGateway(t *testing.T) {
+	joined := make(chan struct{})
+	inputSent := make(chan struct{})
+
+	interaction := irctest.Interaction{
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Server: ":Test!~Tester@127.0.0.1 JOIN #Test"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com sync"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com sync"},
+			irctest.InteractionLine{Callback: func() error {
+				close(joined)
+
+				select {
+				case <-inputSent:
+					return nil
+				case <-time.After(time.Second * 5):
+					return errors.New("timed out waiting for input")
+				}
+			}},
+			irctest.InteractionLine{Client: "PRIVMSG #Test :Hello from the web"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 PRIVMSG #Test :Hello from IRC"},
+			irctest.InteractionLine{Server: ":testserver.example.com 306 Test :You have been marked as being away"},
+		),
+	}
+	addr, err := interaction.Listen()
+	if err != nil {
+		t.Fatal("Listen:", err)
+	}
+
+	manager := irc.NewManager(context.Background())
+	manager.AddHandler(handlers.Input)
+	client := manager.New(irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	}, addr, false)
+
+	if errs := manager.ConnectAll(); len(errs) > 0 {
+		t.Fatal("ConnectAll:", errs)
+	}
+
+	select {
+	case <-joined:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for join")
+	}
+
+	server := httptest.NewServer(newGateway(manager))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal("Dial:", err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+
+	msg := message{}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal("Read state:", err)
+	}
+	if msg.Type != "state" || msg.ClientID != client.ID() || msg.State == nil {
+		t.Fatalf("Expected state message, got %#+v", msg)
+	}
+
+	channelID := ""
+	for _, target := range msg.State.Targets {
+		if target.Kind == "channel" && target.Name == "#Test" {
+			channelID = target.ID
+		}
+	}
+	if channelID == "" {
+		t.Fatalf("State should contain #Test: %#+v", msg.State.Targets)
+	}
+
+	err = conn.WriteJSON(message{Type: "input", ClientID: client.ID(), TargetID: channelID, Text: "Hello from the web"})
+	if err != nil {
+		t.Fatal("Write input:", err)
+	}
+	close(inputSent)
+
+	for received := false; !received; {
+		msg := message{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal("Read event:", err)
+		}
+		if msg.Type != "event" || msg.Event == nil {
+			continue
+		}
+		if msg.Event.Kind() == "hook" || msg.Event.Kind() == "input" {
+			t.Errorf("Internal event should not be forwarded: %s", msg.Event.Name())
+		}
+		if msg.Event.Name() != "packet.privmsg" || msg.Event.Nick != "Gisle" {
+			continue
+		}
+
+		if msg.Event.Text != "Hello from IRC" {
+			t.Errorf("Wrong text: %#+v", msg.Event.Text)
+		}
+		if ids := msg.Event.TargetIDs(); len(ids) != 1 || ids[0] != channelID {
+			t.Errorf("Event should be in #Test: %#+v", ids)
+		}
+
+		received = true
+	}
+
+	for {
+		msg := message{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal("Read self event:", err)
+		}
+		if msg.Type == "event" && msg.Event != nil && msg.Event.Name() == "self.away" {
+			break
+		}
+	}
+
+	interaction.Wait()
+	if interaction.Failure != nil {
+		t.Errorf("Interaction failed: %#+v", interaction.Failure)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/handlers"
+)
+
+var flagListen = flag.String("listen", "127.0.0.1:8080", "The address to serve the websocket on")
+var flagConfig = flag.String("config", "gateway.json", "The config file with the networks to connect to")
+
+// gatewayConfig is the config file's format.
+type gatewayConfig struct {
+	Networks []struct {
+		Server string     `json:"server"`
+		SSL    bool       `json:"ssl"`
+		Config irc.Config `json:"config"`
+	} `json:"networks"`
+}
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	flag.Parse()
+
+	file, err := os.Open(*flagConfig)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to open config: %s\n", err)
+		os.Exit(1)
+	}
+	config := gatewayConfig{}
+	err = json.NewDecoder(file).Decode(&config)
+	_ = file.Close()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to parse config: %s\n", err)
+		os.Exit(1)
+	}
+
+	manager := irc.NewManager(ctx)
+	manager.AddHandler(handlers.Input)
+	manager.AddHandler(handlers.MRoleplay)
+	manager.AddHandler(handlers.CTCP)
+
+	for _, network := range config.Networks {
+		manager.New(network.Config, network.Server, network.SSL)
+	}
+
+	for id, err := range manager.ConnectAll() {
+		log.Printf("Client %s failed to connect: %s", id, err)
+	}
+
+	go func() {
+		exitSignal := make(chan os.Signal, 1)
+		signal.Notify(exitSignal, os.Interrupt, syscall.SIGTERM)
+
+		<-exitSignal
+
+		// Give the clients some time to send the QUIT before exiting.
+		quitCtx, quitCancel := context.WithTimeout(ctx, time.Second*5)
+		disconnects := make([]<-chan *irc.Event, 0, len(config.Networks))
+		for _, client := range manager.Clients() {
+			if client.Connected() {
+				disconnects = append(disconnects, client.Subscribe(quitCtx, irc.EventFilter{Patterns: []string{"client.disconnect"}}))
+			}
+		}
+
+		manager.QuitAll("Goodnight.")
+		for _, disconnect := range disconnects {
+			select {
+			case <-disconnect:
+			case <-quitCtx.Done():
+			}
+		}
+
+		quitCancel()
+		os.Exit(0)
+	}()
+
+	log.Println("Listening on", *flagListen)
+	err = http.ListenAndServe(*flagListen, newGateway(manager))
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to listen: %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -110,7 +110,7 @@ func main() {
 	})
 
 	go func() {
-		exitSignal := make(chan os.Signal)
+		exitSignal := make(chan os.Signal, 1)
 		signal.Notify(exitSignal, os.Interrupt, os.Kill, syscall.SIGTERM)
 
 		<-exitSignal
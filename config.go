@@ -2,6 +2,7 @@ package irc
 
 import (
 	"strconv"
+	"time"
 )
 
 // The Config for an IRC client.
@@ -40,8 +41,19 @@ type Config struct {
 	// Whether to use the server time tag to overwrite event time.
 	UseServerTime bool `json:"useServerTime"`
 
+	// PingInterval is how often to PING the server to measure the lag. Default is 60 seconds.
+	PingInterval time.Duration `json:"pingInterval"`
+
+	// PingTimeout is how long the server can be silent before the connection is considered dead and
+	// closed. Default is 120 seconds.
+	PingTimeout time.Duration `json:"pingTimeout"`
+
 	// Use SASL authorization if supported.
 	SASL *SASLConfig `json:"sasl"`
+
+	// BouncerNetwork is the network ID to bind to when connecting to a bouncer supporting
+	// `soju.im/bouncer-networks`. It's set by Client.SpawnBouncerClient.
+	BouncerNetwork string `json:"bouncerNetwork,omitempty"`
 }
 
 type SASLConfig struct {
@@ -73,5 +85,12 @@ func (config Config) WithDefaults() Config {
 		config.SendRate = 2
 	}
 
+	if config.PingInterval <= 0 {
+		config.PingInterval = time.Second * 60
+	}
+	if config.PingTimeout <= 0 {
+		config.PingTimeout = time.Second * 120
+	}
+
 	return config
 }
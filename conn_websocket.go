@@ -0,0 +1,118 @@
+package irc
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// isWebSocketAddr returns true if the address given to Client.Connect is a WebSocket URL.
+func isWebSocketAddr(addr string) bool {
+	return strings.HasPrefix(addr, "ws://") || strings.HasPrefix(addr, "wss://")
+}
+
+// dialWebSocket connects to an IRC server over WebSocket as specified by IRCv3, which carries one
+// line without the line ending in each message.
+func dialWebSocket(url string, skipVerify bool) (net.Conn, error) {
+	dialer := websocket.Dialer{
+		Proxy:            websocket.DefaultDialer.Proxy,
+		HandshakeTimeout: time.Second * 30,
+		Subprotocols:     []string{"text.ircv3.net", "binary.ircv3.net"},
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: skipVerify,
+		},
+	}
+
+	ws, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Servers that do not pick a subprotocol use text messages.
+	messageType := websocket.TextMessage
+	if ws.Subprotocol() == "binary.ircv3.net" {
+		messageType = websocket.BinaryMessage
+	}
+
+	return &websocketConn{ws: ws, messageType: messageType}, nil
+}
+
+// websocketConn adapts a WebSocket connection to the line-based net.Conn the client expects.
+type websocketConn struct {
+	ws          *websocket.Conn
+	messageType int
+	readBuffer  []byte
+	writeMutex  sync.Mutex
+}
+
+// Read reads the messages as lines ending with a line feed.
+func (conn *websocketConn) Read(p []byte) (int, error) {
+	for len(conn.readBuffer) == 0 {
+		_, data, err := conn.ws.ReadMessage()
+		if err != nil {
+			return 0, err
+		}
+
+		data = bytes.TrimRight(data, "\r\n")
+		if len(data) > 0 {
+			conn.readBuffer = append(data, '\n')
+		}
+	}
+
+	n := copy(p, conn.readBuffer)
+	conn.readBuffer = conn.readBuffer[n:]
+
+	return n, nil
+}
+
+// Write sends each line as a message.
+func (conn *websocketConn) Write(p []byte) (int, error) {
+	conn.writeMutex.Lock()
+	defer conn.writeMutex.Unlock()
+
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if len(line) == 0 {
+			continue
+		}
+
+		if err := conn.ws.WriteMessage(conn.messageType, line); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+func (conn *websocketConn) Close() error {
+	return conn.ws.Close()
+}
+
+func (conn *websocketConn) LocalAddr() net.Addr {
+	return conn.ws.LocalAddr()
+}
+
+func (conn *websocketConn) RemoteAddr() net.Addr {
+	return conn.ws.RemoteAddr()
+}
+
+func (conn *websocketConn) SetDeadline(t time.Time) error {
+	if err := conn.ws.SetReadDeadline(t); err != nil {
+		return err
+	}
+
+	return conn.ws.SetWriteDeadline(t)
+}
+
+func (conn *websocketConn) SetReadDeadline(t time.Time) error {
+	return conn.ws.SetReadDeadline(t)
+}
+
+func (conn *websocketConn) SetWriteDeadline(t time.Time) error {
+	return conn.ws.SetWriteDeadline(t)
+}
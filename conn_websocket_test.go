@@ -0,0 +1,112 @@
+package irc_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/internal/irctest"
+	"github.com/gorilla/websocket"
+)
+
+// websocketBridge relays between WebSocket messages and lines on a TCP connection to the interaction.
+func websocketBridge(t *testing.T, addr string, subprotocol string) *httptest.Server {
+	upgrader := websocket.Upgrader{Subprotocols: []string{subprotocol}}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error("Upgrade:", err)
+			return
+		}
+		defer ws.Close()
+
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Error("Dial:", err)
+			return
+		}
+		defer conn.Close()
+
+		go func() {
+			reader := bufio.NewReader(conn)
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					_ = ws.Close()
+					return
+				}
+
+				_ = ws.WriteMessage(websocket.TextMessage, []byte(strings.TrimRight(line, "\r\n")))
+			}
+		}()
+
+		for {
+			messageType, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			expectedType := websocket.TextMessage
+			if subprotocol == "binary.ircv3.net" {
+				expectedType = websocket.BinaryMessage
+			}
+			if messageType != expectedType {
+				t.Errorf("Expected message type %d, got %d", expectedType, messageType)
+			}
+			if strings.ContainsAny(string(data), "\r\n") {
+				t.Errorf("Message should not contain line endings: %#+v", string(data))
+			}
+
+			_, _ = conn.Write(append(data, '\r', '\n'))
+		}
+	}))
+}
+
+func TestClient_ConnectWebSocket(t *testing.T) {
+	for _, subprotocol := range []string{"text.ircv3.net", "binary.ircv3.net"} {
+		t.Run(subprotocol, func(t *testing.T) {
+			client := irc.New(context.Background(), irc.Config{
+				Nick:     "Test",
+				User:     "Tester",
+				RealName: "...",
+				SendRate: 1000,
+			})
+
+			interaction := irctest.Interaction{
+				Strict: true,
+				Lines: append(irctest.Registration("Test", "Tester", "..."),
+					irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 PRIVMSG Test :Hello over WebSocket"},
+					irctest.InteractionLine{Server: "PING :testserver.example.com"},
+					irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+				),
+			}
+			addr, err := interaction.Listen()
+			if err != nil {
+				t.Fatal("Listen:", err)
+			}
+
+			server := websocketBridge(t, addr, subprotocol)
+			defer server.Close()
+
+			err = client.Connect("ws"+strings.TrimPrefix(server.URL, "http"), false)
+			if err != nil {
+				t.Fatal("Connect:", err)
+			}
+
+			interaction.Wait()
+			if interaction.Failure != nil {
+				t.Fatalf("Interaction failed: %#+v", interaction.Failure)
+			}
+
+			if client.Query("Gisle") == nil {
+				t.Error("Message should have opened a query with Gisle")
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package dcc
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/gissleh/irc"
+)
+
+// ErrChatClosed is returned by Chat.Send if the chat has been closed.
+var ErrChatClosed = errors.New("dcc: chat closed")
+
+// chatMaxLineLength is the longest line Chat.Run will read. Longer lines are dropped.
+const chatMaxLineLength = 8192
+
+// A Chat is a DCC CHAT session with a user, and a target of the kind `dcc-chat`. The messages
+// are emitted as `dcc.message` events in the target.
+type Chat struct {
+	id   string
+	nick string
+
+	mutex  sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+// NewChat creates a chat with the user over the connection. It's not started until Run is called.
+func NewChat(id, nick string, conn net.Conn) *Chat {
+	return &Chat{id: id, nick: nick, conn: conn}
+}
+
+// ID returns a unique ID for the chat target.
+func (chat *Chat) ID() string {
+	return chat.id
+}
+
+// Kind returns "dcc-chat"
+func (chat *Chat) Kind() string {
+	return "dcc-chat"
+}
+
+// Name gets the nick of the user on the other end.
+func (chat *Chat) Name() string {
+	return chat.nick
+}
+
+func (chat *Chat) State() irc.ClientStateTarget {
+	return irc.ClientStateTarget{
+		Kind: "dcc-chat",
+		Name: chat.nick,
+	}
+}
+
+// Handle does nothing, since the chat is not tied to the user's presence on IRC.
+func (chat *Chat) Handle(event *irc.Event, client *irc.Client) {
+
+}
+
+// Closed returns true if the chat has been closed by either end.
+func (chat *Chat) Closed() bool {
+	chat.mutex.Lock()
+	defer chat.mutex.Unlock()
+
+	return chat.closed
+}
+
+// Send sends a line to the other end.
+func (chat *Chat) Send(line string) error {
+	chat.mutex.Lock()
+	defer chat.mutex.Unlock()
+
+	if chat.closed {
+		return ErrChatClosed
+	}
+
+	line = strings.NewReplacer("\r", "", "\n", " ").Replace(line)
+	_, err := chat.conn.Write([]byte(line + "\n"))
+
+	return err
+}
+
+// Close closes the chat.
+func (chat *Chat) Close() error {
+	chat.mutex.Lock()
+	defer chat.mutex.Unlock()
+
+	if chat.closed {
+		return nil
+	}
+	chat.closed = true
+
+	return chat.conn.Close()
+}
+
+// Run reads the lines from the other end and emits them as `dcc.message` events, or `dcc.action` for
+// CTCP ACTIONs, until the chat is closed. The chat is then removed from the client, and `dcc.chat_close`
+// is emitted. Lines longer than 8 KiB are dropped. It blocks, and should be run in a goroutine.
+func (chat *Chat) Run(client *irc.Client) {
+	reader := bufio.NewReaderSize(chat.conn, chatMaxLineLength)
+	overlong := false
+	for {
+		data, err := reader.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			overlong = true
+			continue
+		}
+		if overlong {
+			// This is the end of the line that was too long.
+			overlong = false
+			data = nil
+		}
+		line := strings.TrimRight(string(data), "\r\n")
+
+		if line != "" {
+			event := irc.NewEvent("dcc", "message")
+			if strings.HasPrefix(line, "\x01ACTION ") {
+				event = irc.NewEvent("dcc", "action")
+				line = strings.TrimSuffix(strings.TrimPrefix(line, "\x01ACTION "), "\x01")
+			}
+
+			event.Nick = chat.nick
+			event.Text = line
+			event.AddTarget(chat)
+			client.EmitNonBlocking(event)
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	_ = chat.Close()
+	_, _ = client.RemoveTarget(chat)
+
+	event := irc.NewEvent("dcc", "chat_close")
+	event.Nick = chat.nick
+	event.Args = append(event.Args, chat.id)
+	client.EmitNonBlocking(event)
+}
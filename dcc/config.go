@@ -0,0 +1,79 @@
+package dcc
+
+import (
+	"net"
+	"time"
+)
+
+// Config is the configuration of the DCC handler, which it reads from the `dcc.config` client value.
+type Config struct {
+	// Policy decides which files can be received, and where they are saved.
+	Policy Policy `json:"policy"`
+
+	// PublicIP is the address other users should connect to. If it's not set, the client's host is
+	// used if it's an IP address.
+	PublicIP net.IP `json:"publicIp"`
+
+	// ListenAddr is the local address to listen for connections on. The default is any port on all
+	// interfaces.
+	ListenAddr string `json:"listenAddr"`
+
+	// Passive makes file offers passive, so that the receiver has to listen instead. This is useful
+	// if this client cannot be connected to.
+	Passive bool `json:"passive"`
+
+	// Timeout is how long to wait for the other end to connect, and how long offers are kept before
+	// they expire if they aren't accepted. The default is two minutes.
+	Timeout time.Duration `json:"timeout"`
+
+	// MaxOffers is how many offers can be waiting to be accepted or rejected at once, and
+	// MaxOffersPerNick is how many of them can be from the same user. Offers past the limits are
+	// ignored. The defaults are 20 and 3.
+	MaxOffers        int `json:"maxOffers"`
+	MaxOffersPerNick int `json:"maxOffersPerNick"`
+}
+
+// WithDefaults returns the config with the default values.
+func (config Config) WithDefaults() Config {
+	if config.ListenAddr == "" {
+		config.ListenAddr = ":0"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = time.Minute * 2
+	}
+	if config.MaxOffers <= 0 {
+		config.MaxOffers = 20
+	}
+	if config.MaxOffersPerNick <= 0 {
+		config.MaxOffersPerNick = 3
+	}
+
+	return config
+}
+
+// Listen listens for one connection, and returns the listener and the port to put in the offer. Once
+// accepted, the listener is closed.
+func (config Config) Listen() (listener net.Listener, port int, err error) {
+	listener, err = net.Listen("tcp", config.ListenAddr)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return listener, listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Accept waits for the connection on the listener until the timeout, and closes the listener.
+func (config Config) Accept(listener net.Listener) (net.Conn, error) {
+	defer listener.Close()
+
+	if tcpListener, ok := listener.(*net.TCPListener); ok {
+		_ = tcpListener.SetDeadline(time.Now().Add(config.Timeout))
+	}
+
+	return listener.Accept()
+}
+
+// Dial connects to the address in the offer.
+func (config Config) Dial(offer Offer) (net.Conn, error) {
+	return net.DialTimeout("tcp", offer.Addr(), config.Timeout)
+}
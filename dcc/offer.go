@@ -0,0 +1,198 @@
+// Package dcc implements the Direct Client-to-Client protocol used for sending files and chatting
+// outside of the IRC server. The handler in the handlers package uses it to glue it into the client.
+package dcc
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidOffer is returned when parsing a DCC request that is malformed.
+var ErrInvalidOffer = errors.New("dcc: invalid offer")
+
+// An Offer is a DCC SEND or DCC CHAT request. An offer with port 0 is passive (also called reverse),
+// which means the receiver should listen and reply with the same offer and token, but with its own
+// address.
+type Offer struct {
+	Type     string `json:"type"`
+	Filename string `json:"filename"`
+	IP       net.IP `json:"ip"`
+	Port     int    `json:"port"`
+	Size     int64  `json:"size"`
+	Token    string `json:"token,omitempty"`
+}
+
+// ParseOffer parses the text of a `DCC` CTCP, like `SEND "file name.txt" 3232235777 5000 1024`. Filenames
+// with spaces are quoted, and the size is -1 if it is not given.
+func ParseOffer(text string) (Offer, error) {
+	offer := Offer{Size: -1}
+
+	typ, rest := nextToken(text)
+	offer.Type = strings.ToUpper(typ)
+	if offer.Type != "SEND" && offer.Type != "CHAT" {
+		return Offer{}, ErrInvalidOffer
+	}
+
+	offer.Filename, rest = nextFilename(rest)
+	fields := strings.Fields(rest)
+	if offer.Filename == "" || len(fields) < 2 {
+		return Offer{}, ErrInvalidOffer
+	}
+
+	offer.IP = ParseIP(fields[0])
+	if offer.IP == nil {
+		return Offer{}, ErrInvalidOffer
+	}
+
+	port, err := strconv.Atoi(fields[1])
+	if err != nil || port < 0 || port > 65535 {
+		return Offer{}, ErrInvalidOffer
+	}
+	offer.Port = port
+
+	if len(fields) > 2 {
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil || size < 0 {
+			return Offer{}, ErrInvalidOffer
+		}
+		offer.Size = size
+	}
+
+	if len(fields) > 3 {
+		offer.Token = fields[3]
+	}
+
+	if offer.Port == 0 && offer.Token == "" {
+		return Offer{}, ErrInvalidOffer
+	}
+
+	return offer, nil
+}
+
+// Passive returns true if the offer is a passive one, where the receiver has to listen.
+func (offer Offer) Passive() bool {
+	return offer.Port == 0
+}
+
+// Addr gets the address to connect to.
+func (offer Offer) Addr() string {
+	return net.JoinHostPort(offer.IP.String(), strconv.Itoa(offer.Port))
+}
+
+// String formats the offer as the text of a `DCC` CTCP.
+func (offer Offer) String() string {
+	tokens := []string{offer.Type, formatFilename(offer.Filename), FormatIP(offer.IP), strconv.Itoa(offer.Port)}
+	if offer.Size >= 0 || offer.Token != "" {
+		tokens = append(tokens, strconv.FormatInt(offer.Size, 10))
+	}
+	if offer.Token != "" {
+		tokens = append(tokens, offer.Token)
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+// A Resume is a DCC RESUME or DCC ACCEPT request. The receiver of a file sends RESUME to ask the sender
+// to start from the position, and the sender agrees by replying ACCEPT with the same values.
+type Resume struct {
+	Type     string `json:"type"`
+	Filename string `json:"filename"`
+	Port     int    `json:"port"`
+	Position int64  `json:"position"`
+	Token    string `json:"token,omitempty"`
+}
+
+// ParseResume parses the text of a `DCC RESUME` or `DCC ACCEPT` CTCP.
+func ParseResume(text string) (Resume, error) {
+	resume := Resume{}
+
+	typ, rest := nextToken(text)
+	resume.Type = strings.ToUpper(typ)
+	if resume.Type != "RESUME" && resume.Type != "ACCEPT" {
+		return Resume{}, ErrInvalidOffer
+	}
+
+	resume.Filename, rest = nextFilename(rest)
+	fields := strings.Fields(rest)
+	if len(fields) < 2 {
+		return Resume{}, ErrInvalidOffer
+	}
+
+	port, err := strconv.Atoi(fields[0])
+	if err != nil || port < 0 || port > 65535 {
+		return Resume{}, ErrInvalidOffer
+	}
+	resume.Port = port
+
+	position, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || position < 0 {
+		return Resume{}, ErrInvalidOffer
+	}
+	resume.Position = position
+
+	if len(fields) > 2 {
+		resume.Token = fields[2]
+	}
+
+	return resume, nil
+}
+
+// String formats the request as the text of a `DCC` CTCP.
+func (resume Resume) String() string {
+	tokens := []string{resume.Type, formatFilename(resume.Filename), strconv.Itoa(resume.Port), strconv.FormatInt(resume.Position, 10)}
+	if resume.Token != "" {
+		tokens = append(tokens, resume.Token)
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+// ParseIP parses an IP address in an offer, which is either a IPv4 address as a 32-bit integer, or
+// an IPv6 address in the usual format.
+func ParseIP(s string) net.IP {
+	if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return net.IPv4(byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+
+	return net.ParseIP(s)
+}
+
+// FormatIP formats an IP address for an offer.
+func FormatIP(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return strconv.FormatUint(uint64(ip4[0])<<24|uint64(ip4[1])<<16|uint64(ip4[2])<<8|uint64(ip4[3]), 10)
+	}
+
+	return ip.String()
+}
+
+func nextToken(s string) (token, rest string) {
+	s = strings.TrimLeft(s, " ")
+	if space := strings.IndexByte(s, ' '); space != -1 {
+		return s[:space], s[space+1:]
+	}
+
+	return s, ""
+}
+
+// nextFilename gets a filename, which may be quoted if it contains spaces.
+func nextFilename(s string) (filename, rest string) {
+	s = strings.TrimLeft(s, " ")
+	if strings.HasPrefix(s, "\"") {
+		if end := strings.IndexByte(s[1:], '"'); end != -1 {
+			return s[1 : end+1], s[end+2:]
+		}
+	}
+
+	return nextToken(s)
+}
+
+func formatFilename(filename string) string {
+	if strings.ContainsAny(filename, " \t") {
+		return "\"" + strings.Replace(filename, "\"", "'", -1) + "\""
+	}
+
+	return filename
+}
@@ -0,0 +1,142 @@
+package dcc_test
+
+import (
+	"bytes"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/gissleh/irc/dcc"
+)
+
+func TestParseOffer(t *testing.T) {
+	table := []struct {
+		Text   string
+		Offer  dcc.Offer
+		String string
+		Err    error
+	}{
+		{
+			"SEND file.txt 3232235777 5000 1024",
+			dcc.Offer{Type: "SEND", Filename: "file.txt", IP: net.ParseIP("192.168.1.1"), Port: 5000, Size: 1024},
+			"SEND file.txt 3232235777 5000 1024", nil,
+		},
+		{
+			"SEND \"my file.txt\" 2130706433 0 1024 17",
+			dcc.Offer{Type: "SEND", Filename: "my file.txt", IP: net.ParseIP("127.0.0.1"), Port: 0, Size: 1024, Token: "17"},
+			"SEND \"my file.txt\" 2130706433 0 1024 17", nil,
+		},
+		{
+			"CHAT chat 2130706433 6000",
+			dcc.Offer{Type: "CHAT", Filename: "chat", IP: net.ParseIP("127.0.0.1"), Port: 6000, Size: -1},
+			"CHAT chat 2130706433 6000", nil,
+		},
+		{
+			"send file.bin ::1 6001 10",
+			dcc.Offer{Type: "SEND", Filename: "file.bin", IP: net.ParseIP("::1"), Port: 6001, Size: 10},
+			"SEND file.bin ::1 6001 10", nil,
+		},
+		{"SEND file.txt 2130706433 0 1024", dcc.Offer{}, "", dcc.ErrInvalidOffer},
+		{"SEND file.txt 2130706433 70000", dcc.Offer{}, "", dcc.ErrInvalidOffer},
+		{"SEND file.txt notanip 5000", dcc.Offer{}, "", dcc.ErrInvalidOffer},
+		{"SEND file.txt", dcc.Offer{}, "", dcc.ErrInvalidOffer},
+		{"XMIT file.txt 2130706433 5000", dcc.Offer{}, "", dcc.ErrInvalidOffer},
+	}
+
+	for _, row := range table {
+		t.Run(row.Text, func(t *testing.T) {
+			offer, err := dcc.ParseOffer(row.Text)
+			if err != row.Err {
+				t.Fatalf("Expected error %v, got %v", row.Err, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if offer.Type != row.Offer.Type || offer.Filename != row.Offer.Filename || !offer.IP.Equal(row.Offer.IP) ||
+				offer.Port != row.Offer.Port || offer.Size != row.Offer.Size || offer.Token != row.Offer.Token {
+				t.Errorf("Expected %#+v, got %#+v", row.Offer, offer)
+			}
+			if offer.String() != row.String {
+				t.Errorf("Expected %#+v, got %#+v", row.String, offer.String())
+			}
+		})
+	}
+}
+
+func TestParseResume(t *testing.T) {
+	resume, err := dcc.ParseResume("RESUME \"my file.txt\" 5000 512 17")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := dcc.Resume{Type: "RESUME", Filename: "my file.txt", Port: 5000, Position: 512, Token: "17"}
+	if resume != expected {
+		t.Errorf("Expected %#+v, got %#+v", expected, resume)
+	}
+
+	resume.Type = "ACCEPT"
+	if resume.String() != "ACCEPT \"my file.txt\" 5000 512 17" {
+		t.Errorf("Wrong string: %#+v", resume.String())
+	}
+
+	if _, err := dcc.ParseResume("RESUME file.txt 5000"); err != dcc.ErrInvalidOffer {
+		t.Errorf("Expected ErrInvalidOffer, got %v", err)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	policy := dcc.Policy{Dir: "/downloads", MaxSize: 1000}
+
+	paths := []struct {
+		Filename string
+		Path     string
+		Err      error
+	}{
+		{"file.txt", "/downloads/file.txt", nil},
+		{"../../etc/passwd", "/downloads/passwd", nil},
+		{"C:\\Windows\\evil.exe", "/downloads/evil.exe", nil},
+		{".bashrc", "/downloads/bashrc", nil},
+		{"bad\x00name?.txt", "/downloads/bad_name_.txt", nil},
+		{"..", "", dcc.ErrInvalidFilename},
+		{"dir/", "", dcc.ErrInvalidFilename},
+	}
+	for _, row := range paths {
+		path, err := policy.Path(row.Filename)
+		if err != row.Err || path != filepath.FromSlash(row.Path) {
+			t.Errorf("%#+v: expected (%#+v, %v), got (%#+v, %v)", row.Filename, row.Path, row.Err, path, err)
+		}
+	}
+
+	if _, err := (dcc.Policy{}).Path("file.txt"); err != dcc.ErrNoDirectory {
+		t.Errorf("Expected ErrNoDirectory, got %v", err)
+	}
+
+	if err := policy.Check(dcc.Offer{Size: 1000}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := policy.Check(dcc.Offer{Size: 1001}); err != dcc.ErrFileTooLarge {
+		t.Errorf("Expected ErrFileTooLarge, got %v", err)
+	}
+	if err := policy.Check(dcc.Offer{Size: -1}); err != dcc.ErrUnknownSize {
+		t.Errorf("Expected ErrUnknownSize, got %v", err)
+	}
+	if err := (dcc.Policy{AllowUnknownSize: true}).Check(dcc.Offer{Size: -1}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := (dcc.Policy{AllowUnknownSize: true, MaxSize: 1000}).Check(dcc.Offer{Size: -1}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	buffer := &bytes.Buffer{}
+	w := policy.Limit(buffer, 900)
+	if _, err := w.Write(make([]byte, 100)); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if _, err := w.Write(make([]byte, 1)); err != dcc.ErrFileTooLarge {
+		t.Errorf("Expected ErrFileTooLarge, got %v", err)
+	}
+	if buffer.Len() != 100 {
+		t.Errorf("Expected 100 bytes to be written, got %d", buffer.Len())
+	}
+}
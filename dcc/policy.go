@@ -0,0 +1,105 @@
+package dcc
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoDirectory is returned by Policy.Path if the policy has no directory to save files in.
+var ErrNoDirectory = errors.New("dcc: no directory for received files")
+
+// ErrInvalidFilename is returned by Policy.Path if nothing is left of the filename after sanitizing it.
+var ErrInvalidFilename = errors.New("dcc: invalid filename")
+
+// ErrFileTooLarge is returned by Policy.Check if the offered file is larger than the policy allows, and
+// by the writer from Policy.Limit once more than that has been written.
+var ErrFileTooLarge = errors.New("dcc: file too large")
+
+// ErrUnknownSize is returned by Policy.Check if the offer does not say the size, and the policy
+// does not allow that.
+var ErrUnknownSize = errors.New("dcc: file size unknown")
+
+// A Policy decides which file offers can be accepted, and where they are saved.
+type Policy struct {
+	// Dir is the directory to save received files in. Files are never saved outside of it.
+	Dir string `json:"dir"`
+
+	// MaxSize is the size of the largest file that can be accepted. Zero means no limit.
+	MaxSize int64 `json:"maxSize"`
+
+	// AllowUnknownSize allows accepting offers without a size. MaxSize still applies to them, but
+	// since it cannot be checked up front, the transfer fails once it has received more than that.
+	AllowUnknownSize bool `json:"allowUnknownSize"`
+}
+
+// Check returns an error if the offer can not be accepted.
+func (policy Policy) Check(offer Offer) error {
+	if offer.Size < 0 {
+		if !policy.AllowUnknownSize {
+			return ErrUnknownSize
+		}
+
+		return nil
+	}
+
+	if policy.MaxSize > 0 && offer.Size > policy.MaxSize {
+		return ErrFileTooLarge
+	}
+
+	return nil
+}
+
+// Limit wraps the writer for a file that is received from the position, so that writing past MaxSize
+// fails with ErrFileTooLarge. The writer is returned as it is if there's no limit.
+func (policy Policy) Limit(w io.Writer, position int64) io.Writer {
+	if policy.MaxSize <= 0 {
+		return w
+	}
+
+	return &limitWriter{w: w, remaining: policy.MaxSize - position}
+}
+
+// Path gets the path to save the offered file under. Only the base name of the filename is used, and
+// anything that could make it hidden or special is removed.
+func (policy Policy) Path(filename string) (string, error) {
+	if policy.Dir == "" {
+		return "", ErrNoDirectory
+	}
+
+	filename = strings.Replace(filename, "\\", "/", -1)
+	if slash := strings.LastIndexByte(filename, '/'); slash != -1 {
+		filename = filename[slash+1:]
+	}
+
+	filename = strings.Map(func(r rune) rune {
+		if r < 32 || r == 127 || strings.ContainsRune("<>:\"|?*", r) {
+			return '_'
+		}
+
+		return r
+	}, filename)
+	filename = strings.TrimLeft(strings.TrimSpace(filename), ".")
+	if filename == "" {
+		return "", ErrInvalidFilename
+	}
+
+	return filepath.Join(policy.Dir, filename), nil
+}
+
+type limitWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > w.remaining {
+		return 0, ErrFileTooLarge
+	}
+
+	n, err := w.w.Write(p)
+	w.remaining -= int64(n)
+
+	return n, err
+}
@@ -0,0 +1,145 @@
+package dcc
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+)
+
+// IdleTimeout is how long a transfer can go without any progress before it's aborted.
+var IdleTimeout = time.Minute * 2
+
+// Receive reads a file from the connection into w, starting at the position. For every chunk it
+// receives, it acknowledges the total received so far like the protocol requires. If the size is
+// known, it returns once that much has been received, otherwise it reads until the sender closes
+// the connection. The progress function is called with the total received after each chunk.
+func Receive(ctx context.Context, conn net.Conn, w io.Writer, position, size int64, progress func(total int64)) error {
+	stop := closeOnDone(ctx, conn)
+	defer stop()
+
+	buffer := make([]byte, 32*1024)
+	ack := make([]byte, 4)
+	total := position
+
+	for size < 0 || total < size {
+		_ = conn.SetDeadline(time.Now().Add(IdleTimeout))
+
+		n, err := conn.Read(buffer)
+		if n > 0 {
+			if _, err := w.Write(buffer[:n]); err != nil {
+				return err
+			}
+			total += int64(n)
+
+			binary.BigEndian.PutUint32(ack, uint32(total))
+			if _, err := conn.Write(ack); err != nil {
+				return contextErr(ctx, err)
+			}
+
+			if progress != nil {
+				progress(total)
+			}
+		}
+
+		if err == io.EOF {
+			if size >= 0 && total < size {
+				return io.ErrUnexpectedEOF
+			}
+
+			return nil
+		} else if err != nil {
+			return contextErr(ctx, err)
+		}
+	}
+
+	return nil
+}
+
+// Send writes a file from r to the connection, where r should already be at the position. It returns
+// once the receiver has acknowledged the whole file, or closed the connection after everything was sent.
+// The progress function is called with the total acknowledged by the receiver.
+func Send(ctx context.Context, conn net.Conn, r io.Reader, position, size int64, progress func(total int64)) error {
+	stop := closeOnDone(ctx, conn)
+	defer stop()
+
+	acked := make(chan error, 1)
+	go func() {
+		ack := make([]byte, 4)
+		for {
+			if _, err := io.ReadFull(conn, ack); err != nil {
+				acked <- err
+				return
+			}
+
+			total := int64(binary.BigEndian.Uint32(ack))
+			if size >= 0 {
+				// The acknowledgements wrap around at 4 GiB, so only the lower bits can be compared.
+				total = size - int64(uint32(size)-uint32(total))
+			}
+			if progress != nil {
+				progress(total)
+			}
+
+			if size >= 0 && total >= size {
+				acked <- nil
+				return
+			}
+		}
+	}()
+
+	buffer := make([]byte, 32*1024)
+	sent := position
+	for size < 0 || sent < size {
+		_ = conn.SetDeadline(time.Now().Add(IdleTimeout))
+
+		n, err := r.Read(buffer)
+		if n > 0 {
+			if _, err := conn.Write(buffer[:n]); err != nil {
+				return contextErr(ctx, err)
+			}
+			sent += int64(n)
+		}
+
+		if err == io.EOF {
+			if size >= 0 && sent < size {
+				return io.ErrUnexpectedEOF
+			}
+
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(IdleTimeout))
+	err := <-acked
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil
+	}
+
+	return contextErr(ctx, err)
+}
+
+// closeOnDone closes the connection if the context is done before stop is called.
+func closeOnDone(ctx context.Context, conn net.Conn) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func contextErr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	return err
+}
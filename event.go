@@ -3,9 +3,17 @@ package irc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 )
 
+// ErrUnsupportedEventVersion is returned when unmarshaling an event encoded by a newer version of this package.
+var ErrUnsupportedEventVersion = errors.New("irc: unsupported event JSON version")
+
+// eventJSONVersion is the version of the event JSON representation, which is increased when
+// it changes in a way older versions cannot decode.
+const eventJSONVersion = 1
+
 // An Event is any thing that passes through the irc client's event loop. It's not thread safe, because it's processed
 // in sequence and should not be used off the goroutine that processed it.
 type Event struct {
@@ -26,8 +34,17 @@ type Event struct {
 	cancel           context.CancelFunc
 	preventedDefault bool
 	hidden           bool
+	replayed         bool
 
-	targets []Target
+	targets    []Target
+	targetRefs []eventTargetRef
+}
+
+// eventTargetRef refers to a target of a stored event that has not been resolved against a client.
+type eventTargetRef struct {
+	ID   string
+	Kind string
+	Name string
 }
 
 // NewEvent makes a new event with Kind, Verb, Time set and Args and Tags initialized.
@@ -94,6 +111,11 @@ func (event *Event) PreventDefault() {
 	event.preventedDefault = true
 }
 
+// DefaultPrevented returns true if PreventDefault has been called.
+func (event *Event) DefaultPrevented() bool {
+	return event.preventedDefault
+}
+
 // Hide will not stop propagation, but it will allow output handlers to know not to
 // render it.
 func (event *Event) Hide() {
@@ -105,6 +127,12 @@ func (event *Event) Hidden() bool {
 	return event.hidden
 }
 
+// Replayed returns true if the event is replayed from an EventStore by Client.Replay. Replayed
+// events are only passed to the handlers, and do not affect the client's state.
+func (event *Event) Replayed() bool {
+	return event.replayed
+}
+
 // Arg gets the argument by index, counting the trailing as the last argument. The rationale
 // behind it is that some servers may use it for the last argument in JOINs and such.
 func (event *Event) Arg(index int) string {
@@ -172,7 +200,20 @@ func (event *Event) StatusTarget() *Status {
 	return target.(*Status)
 }
 
+// TargetIDs gets the IDs of the event's targets. For an unmarshaled event that has not been resolved,
+// it gets the IDs it was marshaled with.
 func (event *Event) TargetIDs() []string {
+	if len(event.targets) == 0 && len(event.targetRefs) > 0 {
+		ids := make([]string, 0, len(event.targetRefs))
+		for _, ref := range event.targetRefs {
+			if ref.ID != "" {
+				ids = append(ids, ref.ID)
+			}
+		}
+
+		return ids
+	}
+
 	ids := make([]string, 0, len(event.targets))
 	for _, target := range event.targets {
 		ids = append(ids, target.ID())
@@ -181,9 +222,22 @@ func (event *Event) TargetIDs() []string {
 	return ids
 }
 
+// AddTarget adds a target to the event, which is useful when emitting events for targets that are
+// not managed by the client itself.
+func (event *Event) AddTarget(target Target) {
+	event.targets = append(event.targets, target)
+}
+
+// ResolveTargets looks up the targets of an unmarshaled event in the client with TargetByID. Targets
+// the client does not have are left out.
+func (event *Event) ResolveTargets(client *Client) {
+	event.targets = client.resolveTargetRefs(event.targetRefs)
+}
+
 // MarshalJSON makes a JSON object from the event.
 func (event *Event) MarshalJSON() ([]byte, error) {
 	data := eventJSONData{
+		Version:    eventJSONVersion,
 		Name:       event.Name(),
 		Kind:       event.kind,
 		Verb:       event.verb,
@@ -195,14 +249,26 @@ func (event *Event) MarshalJSON() ([]byte, error) {
 		Text:       event.Text,
 		Tags:       event.Tags,
 		RenderTags: event.RenderTags,
+		Targets:    event.TargetIDs(),
 	}
 
-	data.Targets = make([]string, 0, len(event.targets))
-	for _, target := range event.targets {
-		data.Targets = append(data.Targets, target.ID())
+	return json.Marshal(data)
+}
+
+// UnmarshalJSON restores an event from JSON made by MarshalJSON. The targets are only known by
+// their IDs until ResolveTargets is called.
+func (event *Event) UnmarshalJSON(b []byte) error {
+	data := eventJSONData{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+	if data.Version > eventJSONVersion {
+		return ErrUnsupportedEventVersion
 	}
 
-	return json.Marshal(data)
+	*event = eventFromJSONData(data)
+
+	return nil
 }
 
 func (event *Event) Copy() *Event {
@@ -225,11 +291,15 @@ func (event *Event) Copy() *Event {
 	if len(event.targets) > 0 {
 		eventCopy.targets = append(event.targets[:0:0], event.targets...)
 	}
+	if len(event.targetRefs) > 0 {
+		eventCopy.targetRefs = append(event.targetRefs[:0:0], event.targetRefs...)
+	}
 
 	return &eventCopy
 }
 
 type eventJSONData struct {
+	Version    int               `json:"version"`
 	Name       string            `json:"name"`
 	Kind       string            `json:"kind"`
 	Verb       string            `json:"verb"`
@@ -243,3 +313,33 @@ type eventJSONData struct {
 	Targets    []string          `json:"targets"`
 	RenderTags map[string]string `json:"renderTags"`
 }
+
+// eventFromJSONData restores an event from its JSON representation. The targets are not resolved.
+func eventFromJSONData(data eventJSONData) Event {
+	event := NewEvent(data.Kind, data.Verb)
+	event.name = data.Name
+	if event.name == "" {
+		event.name = event.kind + "." + event.verb
+	}
+
+	event.Time = data.Time
+	event.Nick = data.Nick
+	event.User = data.User
+	event.Host = data.Host
+	event.Text = data.Text
+	if data.Args != nil {
+		event.Args = data.Args
+	}
+	if data.Tags != nil {
+		event.Tags = data.Tags
+	}
+	if data.RenderTags != nil {
+		event.RenderTags = data.RenderTags
+	}
+
+	for _, id := range data.Targets {
+		event.targetRefs = append(event.targetRefs, eventTargetRef{ID: id})
+	}
+
+	return event
+}
@@ -0,0 +1,71 @@
+package irc_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/gissleh/irc"
+)
+
+func TestEvent_UnmarshalJSON(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+	})
+
+	event := irc.NewErrorEventTarget(client.Status(), "test", "Something happened", "test_happened", nil)
+	event.Nick = "Gisle"
+	event.Args = append(event.Args, "#Test")
+	event.Tags["msgid"] = "abc"
+	event.RenderTags["playback"] = "true"
+
+	data, err := json.Marshal(&event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := irc.Event{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Kind() != "error" || decoded.Verb() != "test" || decoded.Name() != "error.test" {
+		t.Errorf("Wrong name: %s (%s, %s)", decoded.Name(), decoded.Kind(), decoded.Verb())
+	}
+	if decoded.Nick != "Gisle" || decoded.Text != "Something happened" || decoded.Arg(0) != "#Test" {
+		t.Errorf("Wrong fields: %#+v", decoded)
+	}
+	if !decoded.Time.Equal(event.Time) {
+		t.Errorf("Wrong time: %s != %s", decoded.Time, event.Time)
+	}
+	if decoded.Tags["msgid"] != "abc" || decoded.RenderTags["playback"] != "true" {
+		t.Errorf("Wrong tags: %#+v %#+v", decoded.Tags, decoded.RenderTags)
+	}
+
+	ids := decoded.TargetIDs()
+	if len(ids) != 1 || ids[0] != client.Status().ID() {
+		t.Errorf("Wrong target IDs: %#+v", ids)
+	}
+	if decoded.StatusTarget() != nil {
+		t.Error("Targets should not be resolved before ResolveTargets")
+	}
+
+	decoded.ResolveTargets(client)
+	if decoded.StatusTarget() != client.Status() {
+		t.Error("Target should be resolved to the client's status")
+	}
+
+	reencoded, err := json.Marshal(&decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(reencoded) != string(data) {
+		t.Errorf("Round trip mismatch:\n%s\n%s", data, reencoded)
+	}
+
+	if err := json.Unmarshal([]byte(`{"version":99,"kind":"packet","verb":"PRIVMSG"}`), &decoded); err != irc.ErrUnsupportedEventVersion {
+		t.Errorf("Expected ErrUnsupportedEventVersion, got %v", err)
+	}
+}
@@ -2,4 +2,7 @@ module github.com/gissleh/irc
 
 go 1.12
 
-require github.com/stretchr/testify v1.6.1
+require (
+	github.com/gorilla/websocket v1.4.2
+	github.com/stretchr/testify v1.6.1
+)
@@ -1,5 +1,5 @@
 package irc
 
 // A Handler is a function that is part of the irc event loop. It will receive all
-// events.
+// events, or the ones matching the pattern it was added with through Client.On.
 type Handler func(event *Event, client *Client)
@@ -1,17 +1,131 @@
 package handlers
 
 import (
-	"github.com/gissleh/irc"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/gissleh/irc"
 )
 
-// CTCP implements the widely used CTCP commands (CLIENTINFO, VERSION, TIME, and PING), as well as the /ping command.
-// It does not implement DCC.
+// CTCPConfig configures the CTCP handler, which reads it from the `ctcp.config` client value.
+type CTCPConfig struct {
+	// Version is the reply to VERSION. The default is the `ctcp.version.reply` client value, or
+	// this package's name and version if that is not set either.
+	Version string `json:"version"`
+
+	// Source, UserInfo and Finger are the replies to SOURCE, USERINFO and FINGER. Empty means that
+	// the request is not answered.
+	Source   string `json:"source"`
+	UserInfo string `json:"userInfo"`
+	Finger   string `json:"finger"`
+
+	// Disabled lists CTCP verbs that should not be answered, like TIME.
+	Disabled []string `json:"disabled"`
+
+	// SenderLimit is how many replies one sender can get within the SenderInterval. The default
+	// is 3 replies per 10 seconds.
+	SenderLimit    int           `json:"senderLimit"`
+	SenderInterval time.Duration `json:"senderInterval"`
+
+	// GlobalLimit is how many replies can be sent in total within the GlobalInterval. The default
+	// is 10 replies per 10 seconds.
+	GlobalLimit    int           `json:"globalLimit"`
+	GlobalInterval time.Duration `json:"globalInterval"`
+}
+
+// WithDefaults returns the config with the default values.
+func (config CTCPConfig) WithDefaults() CTCPConfig {
+	if config.SenderLimit <= 0 {
+		config.SenderLimit = 3
+	}
+	if config.SenderInterval <= 0 {
+		config.SenderInterval = time.Second * 10
+	}
+	if config.GlobalLimit <= 0 {
+		config.GlobalLimit = 10
+	}
+	if config.GlobalInterval <= 0 {
+		config.GlobalInterval = time.Second * 10
+	}
+
+	return config
+}
+
+func (config CTCPConfig) enabled(verb string) bool {
+	for _, disabled := range config.Disabled {
+		if strings.EqualFold(disabled, verb) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ctcpLimiter counts the replies in fixed windows, both per sender and globally.
+type ctcpLimiter struct {
+	globalStart time.Time
+	globalCount int
+	senders     map[string]ctcpWindow
+}
+
+type ctcpWindow struct {
+	start time.Time
+	count int
+}
+
+// allow returns true and counts the reply if the sender can get another reply.
+func (limiter *ctcpLimiter) allow(config CTCPConfig, sender string, now time.Time) bool {
+	if now.Sub(limiter.globalStart) >= config.GlobalInterval {
+		limiter.globalStart = now
+		limiter.globalCount = 0
+	}
+	if limiter.globalCount >= config.GlobalLimit {
+		return false
+	}
+
+	key := strings.ToLower(sender)
+	window := limiter.senders[key]
+	if now.Sub(window.start) >= config.SenderInterval {
+		window = ctcpWindow{start: now}
+	}
+	if window.count >= config.SenderLimit {
+		return false
+	}
+
+	window.count++
+	limiter.senders[key] = window
+	limiter.globalCount++
+
+	// Forget the senders whose windows have passed, so the map doesn't keep growing.
+	if len(limiter.senders) > 256 {
+		for key, window := range limiter.senders {
+			if now.Sub(window.start) >= config.SenderInterval {
+				delete(limiter.senders, key)
+			}
+		}
+	}
+
+	return true
+}
+
+// CTCP implements the widely used CTCP commands (CLIENTINFO, VERSION, TIME, and PING), as well as SOURCE,
+// USERINFO and FINGER if they're configured. It also implements the /ping command, and emits the round
+// trip time as an `info.ping_result` event with the nick and milliseconds as arguments when the reply
+// arrives. The replies are rate limited per sender and in total, see CTCPConfig.
+// DCC is implemented separately by the DCC handler.
 //
 // For every other CTCP command supported, you should expand the `ctcp.clientinfo.reply` client value like above.
 func CTCP(event *irc.Event, client *irc.Client) {
+	// Let other handlers, such as Ignore, take over the event.
+	if event.DefaultPrevented() {
+		return
+	}
+	// Replayed requests have been answered already.
+	if event.Replayed() {
+		return
+	}
+
 	switch event.Name() {
 	case "client.create":
 		if r, ok := client.Value("ctcp.clientinfo.reply").(string); ok {
@@ -21,31 +135,47 @@ func CTCP(event *irc.Event, client *irc.Client) {
 		} else {
 			client.SetValue("ctcp.clientinfo.reply", "ACTION PING TIME VERSION")
 		}
-	case "ctcp.clientinfo":
+	case "ctcp.clientinfo", "ctcp.version", "ctcp.time", "ctcp.ping", "ctcp.source", "ctcp.userinfo", "ctcp.finger":
 		{
-			response, ok := client.Value("ctcp.clientinfo.reply").(string)
+			config, _ := client.Value("ctcp.config").(CTCPConfig)
+			config = config.WithDefaults()
+
+			verb := strings.ToUpper(event.Verb())
+			response := ctcpResponse(client, config, verb, event.Text)
+			if (response == "" && verb != "PING") || !config.enabled(verb) {
+				break
+			}
+
+			limiter, ok := client.Value("ctcp.limiter").(*ctcpLimiter)
 			if !ok {
-				response = "ACTION PING TIME VERSION"
+				limiter = &ctcpLimiter{senders: make(map[string]ctcpWindow, 16)}
+				client.SetValue("ctcp.limiter", limiter)
+			}
+			if !limiter.allow(config, event.Nick, time.Now()) {
+				break
 			}
 
-			client.SendCTCP("CLIENTINFO", event.Nick, true, response)
+			client.SendCTCP(verb, event.Nick, true, response)
 		}
-	case "ctcp.version":
+	case "ctcp-reply.ping":
 		{
-			version := "github.com/gissleh/irc v1.0"
-			if v, ok := client.Value("ctcp.version.reply").(string); ok {
-				version = v
+			sent, err := strconv.ParseInt(event.Text, 10, 64)
+			if err != nil {
+				break
 			}
 
-			client.SendCTCP("VERSION", event.Nick, true, version)
-		}
-	case "ctcp.time":
-		{
-			client.SendCTCP("TIME", event.Nick, true, time.Now().Local().Format(time.RFC1123))
-		}
-	case "ctcp.ping":
-		{
-			client.SendCTCP("PING", event.Nick, true, event.Text)
+			rtt := time.Now().UnixNano()/1000000 - sent
+			if rtt < 0 || rtt > int64(time.Hour/time.Millisecond) {
+				break
+			}
+
+			result := irc.NewEvent("info", "ping_result")
+			result.Nick = event.Nick
+			result.User = event.User
+			result.Host = event.Host
+			result.Args = append(result.Args, event.Nick, strconv.FormatInt(rtt, 10))
+			result.Text = "Ping reply from " + event.Nick + ": " + (time.Duration(rtt) * time.Millisecond).String()
+			client.EmitNonBlocking(result)
 		}
 	case "input.ping":
 		{
@@ -60,3 +190,62 @@ func CTCP(event *irc.Event, client *irc.Client) {
 		}
 	}
 }
+
+// ctcpResponse gets the reply to the CTCP, or an empty string if it should not be answered.
+func ctcpResponse(client *irc.Client, config CTCPConfig, verb, text string) string {
+	switch verb {
+	case "CLIENTINFO":
+		{
+			response, ok := client.Value("ctcp.clientinfo.reply").(string)
+			if !ok {
+				response = "ACTION PING TIME VERSION"
+			}
+
+			verbs := strings.Fields(response)
+			for _, extra := range []struct {
+				verb  string
+				reply string
+			}{
+				{"SOURCE", config.Source},
+				{"USERINFO", config.UserInfo},
+				{"FINGER", config.Finger},
+			} {
+				if extra.reply != "" {
+					verbs = append(verbs, extra.verb)
+				}
+			}
+
+			enabled := verbs[:0]
+			for _, verb := range verbs {
+				if config.enabled(verb) {
+					enabled = append(enabled, verb)
+				}
+			}
+
+			return strings.Join(enabled, " ")
+		}
+	case "VERSION":
+		{
+			if config.Version != "" {
+				return config.Version
+			}
+			if v, ok := client.Value("ctcp.version.reply").(string); ok {
+				return v
+			}
+
+			return "github.com/gissleh/irc v1.0"
+		}
+	case "TIME":
+		return time.Now().Local().Format(time.RFC1123)
+	case "PING":
+		return text
+	case "SOURCE":
+		return config.Source
+	case "USERINFO":
+		return config.UserInfo
+	case "FINGER":
+		return config.Finger
+	}
+
+	return ""
+}
@@ -0,0 +1,145 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/handlers"
+	"github.com/gissleh/irc/internal/irctest"
+)
+
+func TestCTCP(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+	client.SetValue("ctcp.config", handlers.CTCPConfig{
+		Version:     "TestClient 1.0",
+		UserInfo:    "Just testing",
+		Disabled:    []string{"TIME"},
+		SenderLimit: 2,
+	})
+	client.AddHandler(handlers.CTCP)
+
+	results := client.Subscribe(context.Background(), irc.EventFilter{Patterns: []string{"info.ping_result"}})
+	sent := strconv.FormatInt(time.Now().Add(-time.Millisecond*50).UnixNano()/1000000, 10)
+
+	interaction := irctest.Interaction{
+		Strict: true,
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 PRIVMSG Test :\x01CLIENTINFO\x01"},
+			irctest.InteractionLine{Client: "NOTICE Gisle :\x01CLIENTINFO ACTION PING VERSION USERINFO\x01"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 PRIVMSG Test :\x01TIME\x01"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 PRIVMSG Test :\x01SOURCE\x01"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 PRIVMSG Test :\x01VERSION\x01"},
+			irctest.InteractionLine{Client: "NOTICE Gisle :\x01VERSION TestClient 1.0\x01"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 PRIVMSG Test :\x01USERINFO\x01"},
+			irctest.InteractionLine{Server: ":Other!~other@10.32.0.2 PRIVMSG Test :\x01USERINFO\x01"},
+			irctest.InteractionLine{Client: "NOTICE Other :\x01USERINFO Just testing\x01"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 NOTICE Test :\x01PING " + sent + "\x01"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+		),
+	}
+	addr, err := interaction.Listen()
+	if err != nil {
+		t.Fatal("Listen:", err)
+	}
+	if err := client.Connect(addr, false); err != nil {
+		t.Fatal("Connect:", err)
+	}
+
+	interaction.Wait()
+	if interaction.Failure != nil {
+		t.Error("Index:", interaction.Failure.Index)
+		t.Error("Result:", interaction.Failure.Result)
+		for i, line := range interaction.Log {
+			t.Logf("Log[%d] = %#+v", i, line)
+		}
+		t.FailNow()
+	}
+
+	select {
+	case result := <-results:
+		rtt, _ := strconv.Atoi(result.Arg(1))
+		if result.Arg(0) != "Gisle" || rtt < 50 || rtt > 5000 {
+			t.Errorf("Wrong ping result: %#+v", result.Args)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for ping result")
+	}
+}
+
+func TestCTCP_Replayed(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+	client.SetValue("ctcp.config", handlers.CTCPConfig{Version: "TestClient 1.0", SenderLimit: 10})
+	client.AddHandler(handlers.CTCP)
+	client.AddHandler(handlers.DCC)
+
+	requests := client.Subscribe(context.Background(), irc.EventFilter{Patterns: []string{"ctcp.version", "ctcp.dcc", "ctcp-reply.ping"}})
+	results := client.Subscribe(context.Background(), irc.EventFilter{Patterns: []string{"info.ping_result", "dcc.offer"}})
+	sent := strconv.FormatInt(time.Now().UnixNano()/1000000, 10)
+
+	interaction := irctest.Interaction{
+		Strict: true,
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 PRIVMSG Test :\x01VERSION\x01"},
+			irctest.InteractionLine{Client: "NOTICE Gisle :\x01VERSION TestClient 1.0\x01"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 PRIVMSG Test :\x01DCC SEND notes.txt 2130706433 1024 1000\x01"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 NOTICE Test :\x01PING " + sent + "\x01"},
+			irctest.InteractionLine{Callback: func() error {
+				events := make([]*irc.Event, 0, 3)
+				for len(events) < 3 {
+					select {
+					case event := <-requests:
+						events = append(events, event)
+					case <-time.After(time.Second):
+						return errors.New("timed out waiting for the CTCP events")
+					}
+				}
+
+				<-client.Replay(events).Done()
+				return nil
+			}},
+			irctest.InteractionLine{Server: "PING :testserver.example.com"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+		),
+	}
+	addr, err := interaction.Listen()
+	if err != nil {
+		t.Fatal("Listen:", err)
+	}
+	if err := client.Connect(addr, false); err != nil {
+		t.Fatal("Connect:", err)
+	}
+
+	interaction.Wait()
+	if interaction.Failure != nil {
+		t.Error("Index:", interaction.Failure.Index)
+		t.Error("Result:", interaction.Failure.Result)
+		for i, line := range interaction.Log {
+			t.Logf("Log[%d] = %#+v", i, line)
+		}
+		t.FailNow()
+	}
+
+	count := 0
+	for len(results) > 0 {
+		<-results
+		count++
+	}
+	if count != 2 {
+		t.Errorf("Replayed events should not be handled again, got %d results", count)
+	}
+}
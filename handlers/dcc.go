@@ -0,0 +1,667 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/dcc"
+	"github.com/gissleh/irc/ircutil"
+)
+
+var errDCCNoPublicIP = errors.New("dcc: no public IP address to offer, set PublicIP in the config")
+var errDCCFileExists = errors.New("dcc: file already exists")
+var errDCCOfferExpired = errors.New("dcc: offer expired")
+
+// DCC implements DCC SEND (with RESUME and passive offers) and DCC CHAT. It's configured with a dcc.Config
+// in the `dcc.config` client value, and files cannot be received unless its policy has a directory.
+//
+// Offers are emitted as `dcc.offer` events with the transfer ID, type, filename and size as arguments, which
+// can be accepted with `/dcc accept <id>` or rejected with `/dcc reject <id>`. Offers that aren't accepted
+// within the timeout fail, and the number of pending offers is limited, see dcc.Config. Files are sent with
+// `/dcc send <nick> <path>`, and chats are offered with `/dcc chat <nick>`. Transfers emit `dcc.progress`,
+// `dcc.complete` and `dcc.failed` events, while chats are targets of the `dcc-chat` kind that text can be
+// written to like any other target. It must be added before Input for that to work.
+func DCC(event *irc.Event, client *irc.Client) {
+	// Let other handlers, such as Ignore, take over the event.
+	if event.DefaultPrevented() {
+		return
+	}
+	// Replayed requests have been answered already.
+	if event.Replayed() {
+		return
+	}
+
+	switch event.Name() {
+	case "client.create":
+		if r, ok := client.Value("ctcp.clientinfo.reply").(string); ok {
+			if !strings.Contains(r, "DCC") {
+				client.SetValue("ctcp.clientinfo.reply", r+" DCC")
+			}
+		} else {
+			client.SetValue("ctcp.clientinfo.reply", "DCC")
+		}
+
+	case "ctcp.dcc":
+		{
+			if strings.HasPrefix(strings.ToUpper(event.Text), "RESUME ") || strings.HasPrefix(strings.ToUpper(event.Text), "ACCEPT ") {
+				resume, err := dcc.ParseResume(event.Text)
+				if err != nil {
+					client.EmitNonBlocking(irc.NewErrorEvent("dcc", "Invalid DCC request from "+event.Nick, "dcc_invalid", err))
+					break
+				}
+
+				if resume.Type == "RESUME" {
+					dccHandleResume(client, event.Nick, resume)
+				} else {
+					dccHandleAccept(client, event.Nick, resume)
+				}
+
+				break
+			}
+
+			offer, err := dcc.ParseOffer(event.Text)
+			if err != nil {
+				client.EmitNonBlocking(irc.NewErrorEvent("dcc", "Invalid DCC request from "+event.Nick, "dcc_invalid", err))
+				break
+			}
+
+			dccHandleOffer(client, event, offer)
+		}
+
+	case "input.dcc":
+		{
+			event.PreventDefault()
+
+			subcommand, rest := ircutil.ParseArgAndText(event.Text)
+			arg, text := ircutil.ParseArgAndText(rest)
+
+			switch strings.ToLower(subcommand) {
+			case "accept":
+				dccAccept(client, event, arg)
+			case "reject", "close":
+				dccClose(client, event, arg)
+			case "send":
+				if arg == "" || text == "" {
+					client.EmitNonBlocking(irc.NewErrorEventTarget(event.Target(), "input", "Usage: /dcc send <nick> <path>", "usage_dcc_send", nil))
+					break
+				}
+
+				dccSend(client, event, arg, text)
+			case "chat":
+				if arg == "" {
+					client.EmitNonBlocking(irc.NewErrorEventTarget(event.Target(), "input", "Usage: /dcc chat <nick>", "usage_dcc_chat", nil))
+					break
+				}
+
+				dccChat(client, event, arg)
+			default:
+				client.EmitNonBlocking(irc.NewErrorEventTarget(event.Target(), "input", "Usage: /dcc <accept|reject|send|chat> ...", "usage_dcc", nil))
+			}
+		}
+
+	case "input.text", "input.me", "input.action":
+		{
+			chat, ok := event.Target("dcc-chat").(*dcc.Chat)
+			if !ok || event.Text == "" {
+				break
+			}
+			event.PreventDefault()
+
+			verb := "message"
+			line := event.Text
+			if event.Verb() != "text" {
+				verb = "action"
+				line = "\x01ACTION " + line + "\x01"
+			}
+
+			if err := chat.Send(line); err != nil {
+				client.EmitNonBlocking(irc.NewErrorEventTarget(chat, "dcc", "Failed to send: "+err.Error(), "dcc_chat_send_failed", err))
+				break
+			}
+
+			echo := irc.NewEvent("dcc", verb)
+			echo.Nick = client.Nick()
+			echo.User = client.User()
+			echo.Host = client.Host()
+			echo.Text = event.Text
+			echo.AddTarget(chat)
+			client.EmitNonBlocking(echo)
+		}
+	}
+}
+
+type dccState struct {
+	mutex     sync.Mutex
+	nextID    int
+	transfers map[string]*dccTransfer
+}
+
+// A dccTransfer is a file transfer or chat in either direction.
+type dccTransfer struct {
+	id        string
+	nick      string
+	offer     dcc.Offer
+	incoming  bool
+	path      string
+	position  int64
+	resuming  bool
+	started   bool
+	connected bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func dccGetState(client *irc.Client) *dccState {
+	if state, ok := client.Value("dcc.state").(*dccState); ok {
+		return state
+	}
+
+	state := &dccState{transfers: make(map[string]*dccTransfer, 8)}
+	client.SetValue("dcc.state", state)
+
+	return state
+}
+
+func dccGetConfig(client *irc.Client) dcc.Config {
+	config, _ := client.Value("dcc.config").(dcc.Config)
+	return config.WithDefaults()
+}
+
+func dccPublicIP(client *irc.Client, config dcc.Config) (net.IP, error) {
+	if config.PublicIP != nil {
+		return config.PublicIP, nil
+	}
+	if ip := net.ParseIP(client.Host()); ip != nil {
+		return ip, nil
+	}
+
+	return nil, errDCCNoPublicIP
+}
+
+func (state *dccState) add(client *irc.Client, transfer *dccTransfer) {
+	state.mutex.Lock()
+	state.nextID++
+	transfer.id = strconv.Itoa(state.nextID)
+	transfer.ctx, transfer.cancel = context.WithCancel(client.Context())
+	state.transfers[transfer.id] = transfer
+	state.mutex.Unlock()
+}
+
+// canOffer returns true if another offer from the nick can be added without going past the limits.
+func (state *dccState) canOffer(nick string, config dcc.Config) bool {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	total, fromNick := 0, 0
+	for _, transfer := range state.transfers {
+		if !transfer.incoming || transfer.started {
+			continue
+		}
+
+		total++
+		if strings.EqualFold(transfer.nick, nick) {
+			fromNick++
+		}
+	}
+
+	return total < config.MaxOffers && fromNick < config.MaxOffersPerNick
+}
+
+func (state *dccState) get(id string) *dccTransfer {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	return state.transfers[id]
+}
+
+func (state *dccState) remove(transfer *dccTransfer) {
+	state.mutex.Lock()
+	delete(state.transfers, transfer.id)
+	state.mutex.Unlock()
+
+	transfer.cancel()
+}
+
+// find finds the transfer with the user that matches the port, or the token if the port is 0.
+func (state *dccState) find(nick string, incoming bool, port int, token string) *dccTransfer {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	for _, transfer := range state.transfers {
+		if transfer.incoming != incoming || !strings.EqualFold(transfer.nick, nick) {
+			continue
+		}
+
+		if port != 0 && transfer.offer.Port == port {
+			return transfer
+		}
+		if port == 0 && token != "" && transfer.offer.Token == token {
+			return transfer
+		}
+	}
+
+	return nil
+}
+
+func dccHandleOffer(client *irc.Client, event *irc.Event, offer dcc.Offer) {
+	state := dccGetState(client)
+
+	// A passive offer with a port is the receiver's reply to a passive offer sent by this client.
+	if offer.Port != 0 && offer.Token != "" {
+		if transfer := state.find(event.Nick, false, 0, offer.Token); transfer != nil {
+			state.mutex.Lock()
+			started := transfer.started
+			transfer.started = true
+			state.mutex.Unlock()
+
+			if !started {
+				go dccRunSend(client, state, transfer, func(config dcc.Config) (net.Conn, error) {
+					return config.Dial(offer)
+				})
+			}
+
+			return
+		}
+	}
+
+	config := dccGetConfig(client)
+	if !state.canOffer(event.Nick, config) {
+		return
+	}
+
+	transfer := &dccTransfer{nick: event.Nick, offer: offer, incoming: true}
+	state.add(client, transfer)
+	time.AfterFunc(config.Timeout, func() {
+		dccExpire(client, state, transfer)
+	})
+
+	offerEvent := irc.NewEvent("dcc", "offer")
+	offerEvent.Nick = event.Nick
+	offerEvent.User = event.User
+	offerEvent.Host = event.Host
+	offerEvent.Args = append(offerEvent.Args, transfer.id, strings.ToLower(offer.Type), offer.Filename, strconv.FormatInt(offer.Size, 10))
+	client.EmitNonBlocking(offerEvent)
+}
+
+// dccExpire removes an offer that is still waiting to be accepted, or for the sender to accept the resume.
+// Passive offers sent by this client expire the same way if the receiver doesn't reply.
+func dccExpire(client *irc.Client, state *dccState, transfer *dccTransfer) {
+	state.mutex.Lock()
+	expired := state.transfers[transfer.id] == transfer && !transfer.started
+	if expired {
+		// Mark it as started, so that it can't be accepted after this.
+		transfer.started = true
+	}
+	state.mutex.Unlock()
+
+	if expired {
+		dccFail(client, state, transfer, errDCCOfferExpired)
+	}
+}
+
+// dccHandleResume handles a receiver's request to resume a file this client is sending.
+func dccHandleResume(client *irc.Client, nick string, resume dcc.Resume) {
+	state := dccGetState(client)
+	transfer := state.find(nick, false, resume.Port, resume.Token)
+	if transfer == nil {
+		return
+	}
+
+	state.mutex.Lock()
+	if transfer.connected || resume.Position >= transfer.offer.Size {
+		state.mutex.Unlock()
+		return
+	}
+	transfer.position = resume.Position
+	state.mutex.Unlock()
+
+	resume.Type = "ACCEPT"
+	client.SendCTCP("DCC", nick, false, resume.String())
+}
+
+// dccHandleAccept handles the sender's acceptance of this client's request to resume.
+func dccHandleAccept(client *irc.Client, nick string, resume dcc.Resume) {
+	state := dccGetState(client)
+	transfer := state.find(nick, true, resume.Port, resume.Token)
+	if transfer == nil {
+		return
+	}
+
+	state.mutex.Lock()
+	if !transfer.resuming || transfer.started {
+		state.mutex.Unlock()
+		return
+	}
+	transfer.resuming = false
+	transfer.started = true
+	state.mutex.Unlock()
+
+	dccStartReceive(client, state, transfer)
+}
+
+func dccAccept(client *irc.Client, event *irc.Event, id string) {
+	state := dccGetState(client)
+	transfer := state.get(id)
+	if transfer == nil || !transfer.incoming {
+		client.EmitNonBlocking(irc.NewErrorEventTarget(event.Target(), "input", "No DCC offer with ID "+id, "dcc_no_offer", nil))
+		return
+	}
+
+	state.mutex.Lock()
+	if transfer.started || transfer.resuming {
+		state.mutex.Unlock()
+		return
+	}
+	state.mutex.Unlock()
+
+	if transfer.offer.Type == "CHAT" {
+		state.mutex.Lock()
+		transfer.started = true
+		state.mutex.Unlock()
+
+		go dccRunChat(client, state, transfer, func(config dcc.Config) (net.Conn, error) {
+			return dccConnectIncoming(client, config, transfer)
+		})
+
+		return
+	}
+
+	config := dccGetConfig(client)
+	err := config.Policy.Check(transfer.offer)
+	if err == nil {
+		transfer.path, err = config.Policy.Path(transfer.offer.Filename)
+	}
+	if err != nil {
+		dccFail(client, state, transfer, err)
+		return
+	}
+
+	// Resume if a part of the file has been received before.
+	if info, err := os.Stat(transfer.path); err == nil {
+		if transfer.offer.Size < 0 || info.Size() >= transfer.offer.Size {
+			dccFail(client, state, transfer, errDCCFileExists)
+			return
+		}
+
+		if info.Size() > 0 {
+			state.mutex.Lock()
+			transfer.position = info.Size()
+			transfer.resuming = true
+			state.mutex.Unlock()
+
+			client.SendCTCP("DCC", transfer.nick, false, dcc.Resume{
+				Type:     "RESUME",
+				Filename: transfer.offer.Filename,
+				Port:     transfer.offer.Port,
+				Position: info.Size(),
+				Token:    transfer.offer.Token,
+			}.String())
+
+			return
+		}
+	}
+
+	state.mutex.Lock()
+	transfer.started = true
+	state.mutex.Unlock()
+
+	dccStartReceive(client, state, transfer)
+}
+
+func dccStartReceive(client *irc.Client, state *dccState, transfer *dccTransfer) {
+	go func() {
+		config := dccGetConfig(client)
+
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if transfer.position > 0 {
+			flags = os.O_WRONLY | os.O_APPEND
+		}
+		file, err := os.OpenFile(transfer.path, flags, 0644)
+		if err != nil {
+			dccFail(client, state, transfer, err)
+			return
+		}
+		defer file.Close()
+
+		conn, err := dccConnectIncoming(client, config, transfer)
+		if err != nil {
+			dccFail(client, state, transfer, err)
+			return
+		}
+		defer conn.Close()
+
+		progress := dccProgress(client, transfer)
+		err = dcc.Receive(transfer.ctx, conn, config.Policy.Limit(file, transfer.position), transfer.position, transfer.offer.Size, progress)
+		if err != nil {
+			dccFail(client, state, transfer, err)
+			return
+		}
+
+		dccComplete(client, state, transfer, transfer.path)
+	}()
+}
+
+// dccConnectIncoming connects to the sender of an offer, or listens for the connection if the offer is passive.
+func dccConnectIncoming(client *irc.Client, config dcc.Config, transfer *dccTransfer) (net.Conn, error) {
+	if !transfer.offer.Passive() {
+		return config.Dial(transfer.offer)
+	}
+
+	ip, err := dccPublicIP(client, config)
+	if err != nil {
+		return nil, err
+	}
+	listener, port, err := config.Listen()
+	if err != nil {
+		return nil, err
+	}
+
+	reply := transfer.offer
+	reply.IP = ip
+	reply.Port = port
+	client.SendCTCP("DCC", transfer.nick, false, reply.String())
+
+	return config.Accept(listener)
+}
+
+func dccSend(client *irc.Client, event *irc.Event, nick, path string) {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		client.EmitNonBlocking(irc.NewErrorEventTarget(event.Target(), "input", "Cannot send "+path, "dcc_send_invalid_file", err))
+		return
+	}
+
+	config := dccGetConfig(client)
+	ip, err := dccPublicIP(client, config)
+	if err != nil {
+		client.EmitNonBlocking(irc.NewErrorEventTarget(event.Target(), "dcc", err.Error(), "dcc_no_public_ip", err))
+		return
+	}
+
+	state := dccGetState(client)
+	transfer := &dccTransfer{
+		nick: nick,
+		path: path,
+		offer: dcc.Offer{
+			Type:     "SEND",
+			Filename: info.Name(),
+			IP:       ip,
+			Size:     info.Size(),
+		},
+	}
+	state.add(client, transfer)
+
+	if config.Passive {
+		transfer.offer.Token = transfer.id
+		client.SendCTCP("DCC", nick, false, transfer.offer.String())
+		time.AfterFunc(config.Timeout, func() {
+			dccExpire(client, state, transfer)
+		})
+		return
+	}
+
+	listener, port, err := config.Listen()
+	if err != nil {
+		dccFail(client, state, transfer, err)
+		return
+	}
+	transfer.offer.Port = port
+	transfer.started = true
+	client.SendCTCP("DCC", nick, false, transfer.offer.String())
+
+	go dccRunSend(client, state, transfer, func(config dcc.Config) (net.Conn, error) {
+		return config.Accept(listener)
+	})
+}
+
+func dccRunSend(client *irc.Client, state *dccState, transfer *dccTransfer, connect func(config dcc.Config) (net.Conn, error)) {
+	conn, err := connect(dccGetConfig(client))
+	if err != nil {
+		dccFail(client, state, transfer, err)
+		return
+	}
+	defer conn.Close()
+
+	state.mutex.Lock()
+	position := transfer.position
+	transfer.connected = true
+	state.mutex.Unlock()
+
+	file, err := os.Open(transfer.path)
+	if err != nil {
+		dccFail(client, state, transfer, err)
+		return
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(position, 0); err != nil {
+		dccFail(client, state, transfer, err)
+		return
+	}
+
+	progress := dccProgress(client, transfer)
+	err = dcc.Send(transfer.ctx, conn, file, position, transfer.offer.Size, progress)
+	if err != nil {
+		dccFail(client, state, transfer, err)
+		return
+	}
+
+	dccComplete(client, state, transfer, transfer.path)
+}
+
+func dccChat(client *irc.Client, event *irc.Event, nick string) {
+	config := dccGetConfig(client)
+	ip, err := dccPublicIP(client, config)
+	if err != nil {
+		client.EmitNonBlocking(irc.NewErrorEventTarget(event.Target(), "dcc", err.Error(), "dcc_no_public_ip", err))
+		return
+	}
+
+	listener, port, err := config.Listen()
+	if err != nil {
+		client.EmitNonBlocking(irc.NewErrorEventTarget(event.Target(), "dcc", "Failed to listen: "+err.Error(), "dcc_listen_failed", err))
+		return
+	}
+
+	state := dccGetState(client)
+	transfer := &dccTransfer{
+		nick:    nick,
+		started: true,
+		offer:   dcc.Offer{Type: "CHAT", Filename: "chat", IP: ip, Port: port, Size: -1},
+	}
+	state.add(client, transfer)
+
+	client.SendCTCP("DCC", nick, false, transfer.offer.String())
+
+	go dccRunChat(client, state, transfer, func(config dcc.Config) (net.Conn, error) {
+		return config.Accept(listener)
+	})
+}
+
+func dccRunChat(client *irc.Client, state *dccState, transfer *dccTransfer, connect func(config dcc.Config) (net.Conn, error)) {
+	conn, err := connect(dccGetConfig(client))
+	if err != nil {
+		dccFail(client, state, transfer, err)
+		return
+	}
+
+	chat := dcc.NewChat("D"+transfer.id, transfer.nick, conn)
+	if err := client.AddTarget(chat); err != nil {
+		_ = conn.Close()
+		dccFail(client, state, transfer, err)
+		return
+	}
+
+	go func() {
+		<-transfer.ctx.Done()
+		_ = chat.Close()
+	}()
+
+	event := irc.NewEvent("dcc", "chat_open")
+	event.Nick = transfer.nick
+	event.Args = append(event.Args, transfer.id)
+	event.AddTarget(chat)
+	client.EmitNonBlocking(event)
+
+	chat.Run(client)
+	state.remove(transfer)
+}
+
+func dccClose(client *irc.Client, event *irc.Event, id string) {
+	state := dccGetState(client)
+	transfer := state.get(id)
+	if transfer == nil {
+		client.EmitNonBlocking(irc.NewErrorEventTarget(event.Target(), "input", "No DCC transfer with ID "+id, "dcc_no_transfer", nil))
+		return
+	}
+
+	state.remove(transfer)
+}
+
+// dccProgress makes a progress function for the transfer that emits `dcc.progress` at most twice per second.
+func dccProgress(client *irc.Client, transfer *dccTransfer) func(total int64) {
+	lastEmit := time.Time{}
+
+	return func(total int64) {
+		if time.Since(lastEmit) < time.Millisecond*500 && total != transfer.offer.Size {
+			return
+		}
+		lastEmit = time.Now()
+
+		event := irc.NewEvent("dcc", "progress")
+		event.Nick = transfer.nick
+		event.Args = append(event.Args, transfer.id, strconv.FormatInt(total, 10), strconv.FormatInt(transfer.offer.Size, 10))
+		client.EmitNonBlocking(event)
+	}
+}
+
+func dccComplete(client *irc.Client, state *dccState, transfer *dccTransfer, path string) {
+	state.remove(transfer)
+
+	event := irc.NewEvent("dcc", "complete")
+	event.Nick = transfer.nick
+	event.Args = append(event.Args, transfer.id, path)
+	client.EmitNonBlocking(event)
+}
+
+func dccFail(client *irc.Client, state *dccState, transfer *dccTransfer, err error) {
+	cancelled := transfer.ctx.Err() != nil
+	state.remove(transfer)
+
+	if cancelled {
+		return
+	}
+
+	event := irc.NewEvent("dcc", "failed")
+	event.Nick = transfer.nick
+	event.Args = append(event.Args, transfer.id)
+	event.Text = err.Error()
+	client.EmitNonBlocking(event)
+}
@@ -0,0 +1,320 @@
+package handlers_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/dcc"
+	"github.com/gissleh/irc/handlers"
+	"github.com/gissleh/irc/internal/irctest"
+)
+
+func newDCCTestClient(t *testing.T, config dcc.Config) (*irc.Client, <-chan *irc.Event) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+	client.SetValue("dcc.config", config)
+	client.AddHandler(handlers.DCC)
+	client.AddHandler(handlers.Input)
+
+	return client, client.Subscribe(context.Background(), irc.EventFilter{
+		Patterns: []string{"dcc.*"},
+		Buffer:   1024,
+	})
+}
+
+func waitForDCCEvent(t *testing.T, events <-chan *irc.Event, name string) *irc.Event {
+	timeout := time.After(time.Second * 5)
+	for {
+		select {
+		case event := <-events:
+			if event.Name() == "dcc.failed" {
+				t.Fatalf("Transfer failed: %s", event.Text)
+			}
+			if event.Name() == name {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for %s", name)
+			return nil
+		}
+	}
+}
+
+func TestDCC_SendResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "irc-dcc-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 10000)
+	if err := ioutil.WriteFile(filepath.Join(dir, "hello.txt"), content[:5000], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	size := strconv.Itoa(len(content))
+
+	sendErr := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		_ = listener.Close()
+		if err != nil {
+			sendErr <- err
+			return
+		}
+		defer conn.Close()
+
+		sendErr <- dcc.Send(context.Background(), conn, bytes.NewReader(content[5000:]), 5000, int64(len(content)), nil)
+	}()
+
+	client, events := newDCCTestClient(t, dcc.Config{Policy: dcc.Policy{Dir: dir}})
+
+	interaction := irctest.Interaction{
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Server: ":Gisle!~irce@127.0.0.1 PRIVMSG Test :\x01DCC SEND hello.txt 2130706433 " + port + " " + size + "\x01"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com sync"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com sync"},
+			irctest.InteractionLine{Callback: func() error {
+				client.EmitInput("/dcc accept 1", nil)
+				return nil
+			}},
+			irctest.InteractionLine{Client: "PRIVMSG Gisle :\x01DCC RESUME hello.txt " + port + " 5000\x01"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@127.0.0.1 PRIVMSG Test :\x01DCC ACCEPT hello.txt " + port + " 5000\x01"},
+		),
+	}
+	addr, err := interaction.Listen()
+	if err != nil {
+		t.Fatal("Listen:", err)
+	}
+	if err := client.Connect(addr, false); err != nil {
+		t.Fatal("Connect:", err)
+	}
+
+	offer := waitForDCCEvent(t, events, "dcc.offer")
+	if offer.Nick != "Gisle" || offer.Arg(0) != "1" || offer.Arg(1) != "send" || offer.Arg(2) != "hello.txt" || offer.Arg(3) != size {
+		t.Errorf("Wrong offer: %#+v", offer.Args)
+	}
+
+	complete := waitForDCCEvent(t, events, "dcc.complete")
+	if complete.Arg(1) != filepath.Join(dir, "hello.txt") {
+		t.Errorf("Wrong path: %#+v", complete.Arg(1))
+	}
+
+	interaction.Wait()
+	if interaction.Failure != nil {
+		t.Fatalf("Interaction failed: %#+v", interaction.Failure)
+	}
+	if err := <-sendErr; err != nil {
+		t.Fatal("Send:", err)
+	}
+
+	received, err := ioutil.ReadFile(filepath.Join(dir, "hello.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(received, content) {
+		t.Errorf("Received file differs, got %d bytes out of %d", len(received), len(content))
+	}
+}
+
+func TestDCC_Chat(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+	client, events := newDCCTestClient(t, dcc.Config{})
+
+	interaction := irctest.Interaction{
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Server: ":Gisle!~irce@127.0.0.1 PRIVMSG Test :\x01DCC CHAT chat 2130706433 " + port + "\x01"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com sync"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com sync"},
+		),
+	}
+	addr, err := interaction.Listen()
+	if err != nil {
+		t.Fatal("Listen:", err)
+	}
+	if err := client.Connect(addr, false); err != nil {
+		t.Fatal("Connect:", err)
+	}
+
+	offer := waitForDCCEvent(t, events, "dcc.offer")
+	if offer.Arg(1) != "chat" {
+		t.Errorf("Wrong offer: %#+v", offer.Args)
+	}
+	client.EmitInput("/dcc accept "+offer.Arg(0), nil)
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	open := waitForDCCEvent(t, events, "dcc.chat_open")
+	chat, ok := open.Target("dcc-chat").(*dcc.Chat)
+	if !ok || chat.Name() != "Gisle" {
+		t.Fatalf("Chat open should have the chat target: %#+v", open.TargetIDs())
+	}
+	if client.TargetByID(chat.ID()) != chat {
+		t.Error("Chat should be added to the client")
+	}
+
+	_, _ = conn.Write([]byte(strings.Repeat("spam", 10000) + "\nHello there\r\n\x01ACTION waves\x01\n"))
+
+	message := waitForDCCEvent(t, events, "dcc.message")
+	if message.Text != "Hello there" || message.Nick != "Gisle" || message.Target("dcc-chat") == nil {
+		t.Errorf("Wrong message: %#+v", message)
+	}
+	action := waitForDCCEvent(t, events, "dcc.action")
+	if action.Text != "waves" {
+		t.Errorf("Wrong action: %#+v", action)
+	}
+
+	client.EmitInput("General Kenobi", chat)
+	echo := waitForDCCEvent(t, events, "dcc.message")
+	if echo.Nick != "Test" || echo.Text != "General Kenobi" {
+		t.Errorf("Wrong echo: %#+v", echo)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "General Kenobi\n" {
+		t.Errorf("Wrong line: %#+v (%v)", line, err)
+	}
+
+	_ = conn.Close()
+	waitForDCCEvent(t, events, "dcc.chat_close")
+	if client.TargetByID(chat.ID()) != nil {
+		t.Error("Chat should be removed when closed")
+	}
+
+	interaction.Wait()
+}
+
+func TestDCC_OfferLimits(t *testing.T) {
+	client, events := newDCCTestClient(t, dcc.Config{
+		Timeout:          time.Millisecond * 200,
+		MaxOffers:        2,
+		MaxOffersPerNick: 1,
+	})
+
+	interaction := irctest.Interaction{
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Server: ":Gisle!~irce@127.0.0.1 PRIVMSG Test :\x01DCC SEND a.txt 2130706433 1024 100\x01"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@127.0.0.1 PRIVMSG Test :\x01DCC SEND b.txt 2130706433 1025 100\x01"},
+			irctest.InteractionLine{Server: ":Bob!~bob@127.0.0.1 PRIVMSG Test :\x01DCC SEND c.txt 2130706433 1026 100\x01"},
+			irctest.InteractionLine{Server: ":Carol!~carol@127.0.0.1 PRIVMSG Test :\x01DCC SEND d.txt 2130706433 1027 100\x01"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com sync"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com sync"},
+		),
+	}
+	addr, err := interaction.Listen()
+	if err != nil {
+		t.Fatal("Listen:", err)
+	}
+	if err := client.Connect(addr, false); err != nil {
+		t.Fatal("Connect:", err)
+	}
+
+	offered := make(map[string]bool)
+	expired := make(map[string]bool)
+	timeout := time.After(time.Second * 5)
+	for len(expired) < 2 {
+		select {
+		case event := <-events:
+			switch event.Name() {
+			case "dcc.offer":
+				offered[event.Arg(2)] = true
+			case "dcc.failed":
+				if event.Text != "dcc: offer expired" {
+					t.Errorf("Wrong failure: %s", event.Text)
+				}
+				expired[event.Arg(0)] = true
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for offers to expire: %#+v", expired)
+		}
+	}
+
+	if len(offered) != 2 || !offered["a.txt"] || !offered["c.txt"] {
+		t.Errorf("Wrong offers: %#+v", offered)
+	}
+
+	interaction.Wait()
+}
+
+func TestDCC_PassiveSendExpires(t *testing.T) {
+	dir, err := ioutil.TempDir("", "irc-dcc-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "hello.txt")
+	if err := ioutil.WriteFile(path, []byte("Hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, events := newDCCTestClient(t, dcc.Config{
+		Passive:  true,
+		PublicIP: net.IPv4(127, 0, 0, 1),
+		Timeout:  time.Millisecond * 200,
+	})
+
+	interaction := irctest.Interaction{
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Callback: func() error {
+				client.EmitInput("/dcc send Gisle "+path, nil)
+				return nil
+			}},
+			irctest.InteractionLine{Client: "PRIVMSG Gisle :\x01DCC SEND hello.txt 2130706433 0 5 *"},
+		),
+	}
+	addr, err := interaction.Listen()
+	if err != nil {
+		t.Fatal("Listen:", err)
+	}
+	if err := client.Connect(addr, false); err != nil {
+		t.Fatal("Connect:", err)
+	}
+
+	timeout := time.After(time.Second * 5)
+	for {
+		select {
+		case event := <-events:
+			if event.Name() != "dcc.failed" {
+				continue
+			}
+			if event.Text != "dcc: offer expired" {
+				t.Errorf("Wrong failure: %s", event.Text)
+			}
+
+			interaction.Wait()
+			return
+		case <-timeout:
+			t.Fatal("Timed out waiting for the passive offer to expire")
+		}
+	}
+}
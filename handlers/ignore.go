@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/ircutil"
+)
+
+// The event types an IgnoreEntry can be limited to.
+const (
+	IgnoreMessages = "messages"
+	IgnoreCTCP     = "ctcp"
+	IgnoreInvites  = "invites"
+	IgnoreNotices  = "notices"
+)
+
+// IgnoreConfig configures the Ignore handler, which reads it from the `ignore.config` client value.
+// The /ignore and /unignore commands replace the value with an updated copy.
+type IgnoreConfig struct {
+	// Entries are the ignored users.
+	Entries []IgnoreEntry `json:"entries"`
+
+	// PreventDefault makes the handler call PreventDefault on the ignored events in addition to
+	// hiding them.
+	PreventDefault bool `json:"preventDefault"`
+}
+
+// An IgnoreEntry is one ignored user.
+type IgnoreEntry struct {
+	// Mask is a `nick!user@host` glob, see ircutil.MatchMask. Prefixing it with `$a:` matches
+	// the account name instead.
+	Mask string `json:"mask"`
+
+	// Channels limits the entry to messages in these channels. If it's empty, it applies
+	// everywhere.
+	Channels []string `json:"channels,omitempty"`
+
+	// Types limits the entry to some types of events (IgnoreMessages, IgnoreCTCP, IgnoreInvites
+	// and IgnoreNotices). If it's empty, it applies to all of them.
+	Types []string `json:"types,omitempty"`
+
+	// Expires is when the entry stops applying. The zero time means never.
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// Expired returns true if the entry has expired at the given time.
+func (entry IgnoreEntry) Expired(now time.Time) bool {
+	return !entry.Expires.IsZero() && !now.Before(entry.Expires)
+}
+
+// Matches returns true if the entry applies to a sender in the channel (empty if it's not in
+// a channel) for the event type. The casemapping is the CASEMAPPING from ISUPPORT.
+func (entry IgnoreEntry) Matches(sender ircutil.Hostmask, account, channel, eventType, casemapping string) bool {
+	if len(entry.Types) > 0 && !ignoreContains(entry.Types, eventType, "ascii") {
+		return false
+	}
+	if len(entry.Channels) > 0 && (channel == "" || !ignoreContains(entry.Channels, channel, casemapping)) {
+		return false
+	}
+
+	if strings.HasPrefix(entry.Mask, "$a:") {
+		return account != "" && account != "*" && ircutil.CaseFold(entry.Mask[3:], casemapping) == ircutil.CaseFold(account, casemapping)
+	}
+
+	return sender.Matches(entry.Mask, casemapping)
+}
+
+// Ignore hides events from ignored users, see IgnoreConfig. It should be added before the
+// handlers that act on messages, or with a higher priority. If a private message from an
+// ignored user opened a new query, the query is removed again.
+//
+// It also implements the /ignore and /unignore commands:
+//
+//	/ignore [-c #channel] [-t messages,ctcp,invites,notices] [-d duration] <mask|nick|$a:account>
+//	/unignore <mask|nick|$a:account>
+//
+// /ignore without arguments lists the entries as an `info.ignore_list` event.
+func Ignore(event *irc.Event, client *irc.Client) {
+	switch event.Name() {
+	case "input.ignore":
+		{
+			event.PreventDefault()
+
+			config, _ := client.Value("ignore.config").(IgnoreConfig)
+			if strings.TrimSpace(event.Text) == "" {
+				list := irc.NewEvent("info", "ignore_list")
+				for _, entry := range config.Entries {
+					if !entry.Expired(time.Now()) {
+						list.Args = append(list.Args, entry.Mask)
+					}
+				}
+				client.EmitNonBlocking(list)
+				break
+			}
+
+			entry, err := parseIgnoreInput(event.Text)
+			if err != "" {
+				client.EmitNonBlocking(irc.NewErrorEventTarget(event.Target(), "input", err, "usage_ignore", nil))
+				break
+			}
+
+			entries := make([]IgnoreEntry, 0, len(config.Entries)+1)
+			for _, existing := range config.Entries {
+				if !existing.Expired(time.Now()) && !strings.EqualFold(existing.Mask, entry.Mask) {
+					entries = append(entries, existing)
+				}
+			}
+			config.Entries = append(entries, entry)
+			client.SetValue("ignore.config", config)
+
+			added := irc.NewEvent("info", "ignore_added")
+			added.Args = append(added.Args, entry.Mask)
+			added.Text = "Ignoring " + entry.Mask
+			client.EmitNonBlocking(added)
+		}
+	case "input.unignore":
+		{
+			event.PreventDefault()
+
+			mask, _ := ircutil.ParseArgAndText(event.Text)
+			if mask == "" {
+				client.EmitNonBlocking(irc.NewErrorEventTarget(event.Target(), "input", "Usage: /unignore <mask|nick|$a:account>", "usage_unignore", nil))
+				break
+			}
+			mask = ignoreMask(mask)
+
+			config, _ := client.Value("ignore.config").(IgnoreConfig)
+			entries := make([]IgnoreEntry, 0, len(config.Entries))
+			found := false
+			for _, existing := range config.Entries {
+				if strings.EqualFold(existing.Mask, mask) {
+					found = true
+				} else if !existing.Expired(time.Now()) {
+					entries = append(entries, existing)
+				}
+			}
+			if !found {
+				client.EmitNonBlocking(irc.NewErrorEventTarget(event.Target(), "input", "Not ignoring "+mask, "ignore_not_found", nil))
+				break
+			}
+
+			config.Entries = entries
+			client.SetValue("ignore.config", config)
+
+			removed := irc.NewEvent("info", "ignore_removed")
+			removed.Args = append(removed.Args, mask)
+			removed.Text = "No longer ignoring " + mask
+			client.EmitNonBlocking(removed)
+		}
+	default:
+		{
+			eventType, channelName := ignoreEventType(event, client)
+			if eventType == "" || event.Nick == "" || event.Nick == client.Nick() {
+				break
+			}
+
+			config, ok := client.Value("ignore.config").(IgnoreConfig)
+			if !ok || len(config.Entries) == 0 {
+				break
+			}
+
+			sender := ircutil.Hostmask{Nick: event.Nick, User: event.User, Host: event.Host}
+			account := ignoreAccount(event, client, channelName)
+			casemapping := client.ISupport().CaseMapping()
+			now := time.Now()
+			for _, entry := range config.Entries {
+				if entry.Expired(now) || !entry.Matches(sender, account, channelName, eventType, casemapping) {
+					continue
+				}
+
+				event.Hide()
+				if config.PreventDefault {
+					event.PreventDefault()
+				}
+
+				if spawned, ok := event.RenderTags["spawned"]; ok {
+					if query := event.QueryTarget(); query != nil && query.ID() == spawned {
+						_, _ = client.RemoveTarget(query)
+					}
+				}
+
+				break
+			}
+		}
+	}
+}
+
+// ignoreEventType gets the ignore type of the event, and the channel it is in.
+func ignoreEventType(event *irc.Event, client *irc.Client) (eventType string, channelName string) {
+	switch event.Name() {
+	case "packet.privmsg", "ctcp.action":
+		eventType = IgnoreMessages
+	case "packet.notice":
+		eventType = IgnoreNotices
+	case "packet.invite":
+		return IgnoreInvites, event.Arg(1)
+	default:
+		if event.Kind() != "ctcp" && event.Kind() != "ctcp-reply" {
+			return "", ""
+		}
+
+		eventType = IgnoreCTCP
+	}
+
+	// Messages to `@#Channel` are still in the channel.
+	if name := strings.TrimLeft(event.Arg(0), client.ISupport().StatusMsg()); client.ISupport().IsChannel(name) {
+		channelName = name
+	}
+
+	return eventType, channelName
+}
+
+// ignoreAccount gets the sender's account from the account tag, or the channel's user list.
+func ignoreAccount(event *irc.Event, client *irc.Client, channelName string) string {
+	if account, ok := event.Tags["account"]; ok {
+		return account
+	}
+
+	if channelName != "" {
+		if channel := client.Channel(channelName); channel != nil {
+			if user, ok := channel.UserList().User(event.Nick); ok {
+				return user.Account
+			}
+		}
+	}
+
+	return ""
+}
+
+// parseIgnoreInput parses the arguments of /ignore, and returns an error message if they're invalid.
+func parseIgnoreInput(text string) (IgnoreEntry, string) {
+	const usage = "Usage: /ignore [-c #channel] [-t messages,ctcp,invites,notices] [-d duration] <mask|nick|$a:account>"
+
+	entry := IgnoreEntry{}
+	tokens := strings.Fields(text)
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if !strings.HasPrefix(token, "-") {
+			if entry.Mask != "" {
+				return entry, usage
+			}
+
+			entry.Mask = ignoreMask(token)
+			continue
+		}
+
+		if i+1 >= len(tokens) {
+			return entry, usage
+		}
+		i++
+
+		switch token {
+		case "-c":
+			entry.Channels = append(entry.Channels, strings.Split(tokens[i], ",")...)
+		case "-t":
+			for _, eventType := range strings.Split(strings.ToLower(tokens[i]), ",") {
+				switch eventType {
+				case IgnoreMessages, IgnoreCTCP, IgnoreInvites, IgnoreNotices:
+					entry.Types = append(entry.Types, eventType)
+				default:
+					return entry, "Unknown ignore type: " + eventType
+				}
+			}
+		case "-d":
+			duration, err := time.ParseDuration(tokens[i])
+			if err != nil || duration <= 0 {
+				return entry, "Invalid duration: " + tokens[i]
+			}
+
+			entry.Expires = time.Now().Add(duration)
+		default:
+			return entry, usage
+		}
+	}
+
+	if entry.Mask == "" {
+		return entry, usage
+	}
+
+	return entry, ""
+}
+
+// ignoreMask turns a nick into a mask, and leaves masks and accounts as they are.
+func ignoreMask(mask string) string {
+	if strings.HasPrefix(mask, "$a:") || strings.ContainsAny(mask, "!@") {
+		return mask
+	}
+
+	return ircutil.EscapeMask(mask) + "!*@*"
+}
+
+func ignoreContains(list []string, value, casemapping string) bool {
+	value = ircutil.CaseFold(value, casemapping)
+	for _, item := range list {
+		if ircutil.CaseFold(item, casemapping) == value {
+			return true
+		}
+	}
+
+	return false
+}
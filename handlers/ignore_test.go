@@ -0,0 +1,149 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/handlers"
+	"github.com/gissleh/irc/internal/irctest"
+)
+
+func TestIgnore(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+	client.SetValue("ignore.config", handlers.IgnoreConfig{
+		Entries: []handlers.IgnoreEntry{
+			{Mask: "$a:spammer"},
+			{Mask: "Old!*@*", Expires: time.Now().Add(-time.Minute)},
+		},
+	})
+	client.AddHandler(handlers.Ignore)
+
+	events := client.Subscribe(context.Background(), irc.EventFilter{Patterns: []string{"packet.privmsg", "packet.notice"}})
+
+	interaction := irctest.Interaction{
+		Strict: true,
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Callback: func() error {
+				<-client.EmitInput("/ignore -c #Test -t messages Troll", nil).Done()
+				<-client.EmitInput("/ignore foo\\bar", nil).Done()
+				return nil
+			}},
+			irctest.InteractionLine{Server: ":Troll!~troll@10.32.0.3 PRIVMSG #test :Hidden"},
+			irctest.InteractionLine{Server: ":Troll!~troll@10.32.0.3 PRIVMSG #Other :Visible"},
+			irctest.InteractionLine{Server: ":Troll!~troll@10.32.0.3 NOTICE #Test :Visible"},
+			irctest.InteractionLine{Server: "@account=spammer :Spam!~spam@10.32.0.4 PRIVMSG Test :Hidden"},
+			irctest.InteractionLine{Server: ":Old!~old@10.32.0.5 PRIVMSG Test :Visible"},
+			irctest.InteractionLine{Server: ":foo\\bar!~foo@10.32.0.6 PRIVMSG #Test :Hidden"},
+			irctest.InteractionLine{Server: ":foobar!~foo@10.32.0.7 PRIVMSG #Test :Visible"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+			irctest.InteractionLine{Callback: func() error {
+				<-client.EmitInput("/unignore Troll", nil).Done()
+
+				config := client.Value("ignore.config").(handlers.IgnoreConfig)
+				if len(config.Entries) != 2 || config.Entries[0].Mask != "$a:spammer" || config.Entries[1].Mask != "foo\\\\bar!*@*" {
+					return errors.New("unexpected entries after /unignore")
+				}
+
+				return nil
+			}},
+		),
+	}
+	addr, err := interaction.Listen()
+	if err != nil {
+		t.Fatal("Listen:", err)
+	}
+	if err := client.Connect(addr, false); err != nil {
+		t.Fatal("Connect:", err)
+	}
+
+	interaction.Wait()
+	if interaction.Failure != nil {
+		t.Error("Index:", interaction.Failure.Index)
+		t.Error("Result:", interaction.Failure.Result)
+		for i, line := range interaction.Log {
+			t.Logf("Log[%d] = %#+v", i, line)
+		}
+		t.FailNow()
+	}
+
+	for i := 0; i < 7; i++ {
+		select {
+		case event := <-events:
+			if event.Hidden() != (event.Text == "Hidden") {
+				t.Errorf("%s from %s: hidden = %t", event.Name(), event.Nick, event.Hidden())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for events")
+		}
+	}
+
+	if client.Query("Spam") != nil {
+		t.Error("Query for ignored user was not removed")
+	}
+	if client.Query("Old") == nil {
+		t.Error("Query for user with expired ignore was not opened")
+	}
+}
+
+func TestIgnore_PreventDefault(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	})
+	client.SetValue("ignore.config", handlers.IgnoreConfig{
+		Entries:        []handlers.IgnoreEntry{{Mask: "Troll!*@*"}},
+		PreventDefault: true,
+	})
+	client.SetValue("ctcp.config", handlers.CTCPConfig{Version: "Test 1.0"})
+	client.AddHandler(handlers.Ignore)
+	client.AddHandler(handlers.CTCP)
+	client.AddHandler(handlers.DCC)
+
+	offers := client.Subscribe(context.Background(), irc.EventFilter{Patterns: []string{"dcc.offer"}})
+
+	interaction := irctest.Interaction{
+		Strict: true,
+		Lines: append(irctest.Registration("Test", "Tester", "..."),
+			irctest.InteractionLine{Server: ":Troll!~troll@10.32.0.3 PRIVMSG Test :\x01VERSION\x01"},
+			irctest.InteractionLine{Server: ":Troll!~troll@10.32.0.3 PRIVMSG Test :\x01DCC SEND spam.exe 2130706433 1024 1000\x01"},
+			irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 PRIVMSG Test :\x01VERSION\x01"},
+			irctest.InteractionLine{Client: "NOTICE Gisle :\x01VERSION Test 1.0\x01"},
+			irctest.InteractionLine{Server: "PING :testserver.example.com"},
+			irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+		),
+	}
+	addr, err := interaction.Listen()
+	if err != nil {
+		t.Fatal("Listen:", err)
+	}
+	if err := client.Connect(addr, false); err != nil {
+		t.Fatal("Connect:", err)
+	}
+
+	interaction.Wait()
+	if interaction.Failure != nil {
+		t.Error("Index:", interaction.Failure.Index)
+		t.Error("Result:", interaction.Failure.Result)
+		for i, line := range interaction.Log {
+			t.Logf("Log[%d] = %#+v", i, line)
+		}
+		t.FailNow()
+	}
+
+	select {
+	case offer := <-offers:
+		t.Errorf("Offer from ignored user was not prevented: %#+v", offer.Args)
+	default:
+	}
+}
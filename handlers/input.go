@@ -8,6 +8,11 @@ import (
 
 // Input handles the default input.
 func Input(event *irc.Event, client *irc.Client) {
+	// Let other handlers take over the input.
+	if event.DefaultPrevented() {
+		return
+	}
+
 	switch event.Name() {
 
 	// /msg sends an action to a target specified before the message.
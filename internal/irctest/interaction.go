@@ -29,8 +29,8 @@ func (interaction *Interaction) Listen() (addr string, err error) {
 	lines := make([]InteractionLine, len(interaction.Lines))
 	copy(lines, interaction.Lines)
 
+	interaction.wg.Add(1)
 	go func() {
-		interaction.wg.Add(1)
 		defer interaction.wg.Done()
 
 		conn, err := listener.Accept()
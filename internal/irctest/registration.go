@@ -0,0 +1,23 @@
+package irctest
+
+// Registration returns the lines of a minimal registration without any caps, ending with a ping/pong
+// to ensure the client is ready. The server advertises the same ISupport as the charybdis server
+// in the client test.
+func Registration(nick, user, realName string) []InteractionLine {
+	return []InteractionLine{
+		{Client: "CAP LS 302"},
+		{Client: "NICK " + nick},
+		{Client: "USER " + user + " 8 * :" + realName},
+		{Server: ":testserver.example.com CAP * LS :"},
+		{Client: "CAP END"},
+		{Server: ":testserver.example.com 001 " + nick + " :Welcome to the TestServer Internet Relay Chat Network " + nick},
+		{Client: "WHO " + nick},
+		{Server: ":testserver.example.com 005 " + nick + " FNC SAFELIST ELIST=CTU MONITOR=100 WHOX ETRACE KNOCK CHANTYPES=#& EXCEPTS INVEX CHANMODES=eIbq,k,flj,CFLNPQcgimnprstz CHANLIMIT=#&:15 :are supported by this server"},
+		{Server: ":testserver.example.com 005 " + nick + " PREFIX=(ov)@+ MAXLIST=bqeI:100 MODES=4 NETWORK=TestServer STATUSMSG=@+ CALLERID=g CASEMAPPING=rfc1459 NICKLEN=30 MAXNICKLEN=31 CHANNELLEN=50 TOPICLEN=390 DEAF=D :are supported by this server"},
+		{Server: ":testserver.example.com 005 " + nick + " TARGMAX=NAMES:1,LIST:1,KICK:1,WHOIS:1,PRIVMSG:4,NOTICE:4,ACCEPT:,MONITOR: EXTBAN=$,&acjmorsuxz| CLIENTVER=3.0 :are supported by this server"},
+		{Server: ":testserver.example.com 352 " + nick + " * ~" + user + " testclient.example.com testserver.example.com " + nick + " H :0 " + realName},
+		{Server: ":testserver.example.com 376 " + nick + " :End of /MOTD command."},
+		{Server: "PING :testserver.example.com"},
+		{Client: "PONG :testserver.example.com"},
+	}
+}
@@ -0,0 +1,134 @@
+package ircformat
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// A Builder builds formatted text for outgoing messages. Each styled method wraps the text
+// in the toggle codes, so styles can be nested by passing the output of another builder.
+// The zero value is ready to use.
+type Builder struct {
+	buffer bytes.Buffer
+}
+
+// Text adds unformatted text.
+func (builder *Builder) Text(text string) *Builder {
+	builder.separateCode(text)
+	builder.buffer.WriteString(text)
+	return builder
+}
+
+// Bold adds bold text.
+func (builder *Builder) Bold(text string) *Builder {
+	return builder.wrap(CodeBold, text)
+}
+
+// Italic adds italic text.
+func (builder *Builder) Italic(text string) *Builder {
+	return builder.wrap(CodeItalic, text)
+}
+
+// Underline adds underlined text.
+func (builder *Builder) Underline(text string) *Builder {
+	return builder.wrap(CodeUnderline, text)
+}
+
+// Strikethrough adds struck out text.
+func (builder *Builder) Strikethrough(text string) *Builder {
+	return builder.wrap(CodeStrikethrough, text)
+}
+
+// Monospace adds monospaced text.
+func (builder *Builder) Monospace(text string) *Builder {
+	return builder.wrap(CodeMonospace, text)
+}
+
+// Reverse adds text with the foreground and background colors swapped.
+func (builder *Builder) Reverse(text string) *Builder {
+	return builder.wrap(CodeReverse, text)
+}
+
+// Color adds colored text. The background can be NoColor. mIRC colors are always written
+// with two digits so that text starting with a number isn't eaten by the color code,
+// and RGB colors are written with the hex color code. If there is no background and the
+// text starts with a comma, a pair of bold codes is put before it so that it isn't read
+// as the background. The same is done after the closing code if the next text would be
+// read as a color.
+func (builder *Builder) Color(foreground, background Color, text string) *Builder {
+	if !foreground.IsSet() {
+		return builder.Text(text)
+	}
+
+	fgIndex, fgIsIndex := foreground.Index()
+	bgIndex, bgIsIndex := background.Index()
+
+	if fgIsIndex && (bgIsIndex || !background.IsSet()) {
+		builder.buffer.WriteString(fmt.Sprintf("%c%02d", CodeColor, fgIndex))
+		if background.IsSet() {
+			builder.buffer.WriteString(fmt.Sprintf(",%02d", bgIndex))
+		} else {
+			builder.separateComma(text)
+		}
+		builder.buffer.WriteString(text)
+		builder.buffer.WriteByte(CodeColor)
+	} else {
+		r, g, b := foreground.RGB()
+		builder.buffer.WriteString(fmt.Sprintf("%c%02X%02X%02X", CodeHexColor, r, g, b))
+		if background.IsSet() {
+			r, g, b := background.RGB()
+			builder.buffer.WriteString(fmt.Sprintf(",%02X%02X%02X", r, g, b))
+		} else {
+			builder.separateComma(text)
+		}
+		builder.buffer.WriteString(text)
+		builder.buffer.WriteByte(CodeHexColor)
+	}
+
+	return builder
+}
+
+// Reset adds a reset code, which clears all formatting.
+func (builder *Builder) Reset() *Builder {
+	builder.buffer.WriteByte(CodeReset)
+	return builder
+}
+
+// String gets the formatted text.
+func (builder *Builder) String() string {
+	return builder.buffer.String()
+}
+
+// separateComma writes two bold codes, which cancel out, if the text starts with a comma.
+func (builder *Builder) separateComma(text string) {
+	if len(text) > 0 && text[0] == ',' {
+		builder.buffer.WriteByte(CodeBold)
+		builder.buffer.WriteByte(CodeBold)
+	}
+}
+
+// separateCode writes two bold codes if the buffer ends with a color code and the text
+// starts with a character that would be read as part of it.
+func (builder *Builder) separateCode(text string) {
+	if builder.buffer.Len() == 0 || len(text) == 0 {
+		return
+	}
+
+	last := builder.buffer.Bytes()[builder.buffer.Len()-1]
+	ch := text[0]
+	isDigit := ch >= '0' && ch <= '9'
+	isHex := isDigit || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+
+	if (last == CodeColor && isDigit) || (last == CodeHexColor && isHex) {
+		builder.buffer.WriteByte(CodeBold)
+		builder.buffer.WriteByte(CodeBold)
+	}
+}
+
+func (builder *Builder) wrap(code byte, text string) *Builder {
+	builder.buffer.WriteByte(code)
+	builder.buffer.WriteString(text)
+	builder.buffer.WriteByte(code)
+
+	return builder
+}
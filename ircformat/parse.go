@@ -0,0 +1,124 @@
+package ircformat
+
+import (
+	"strconv"
+)
+
+// Parse splits the text into spans of equally styled text. Control codes are consumed, and
+// spans with no text are omitted.
+func Parse(text string) []Span {
+	spans := make([]Span, 0, 4)
+	style := Style{}
+	start := 0
+
+	flush := func(end int) {
+		if end > start {
+			spans = append(spans, Span{Style: style, Text: text[start:end]})
+		}
+	}
+
+	for i := 0; i < len(text); i++ {
+		next := i + 1
+
+		switch text[i] {
+		case CodeBold:
+			flush(i)
+			style.Bold = !style.Bold
+		case CodeItalic:
+			flush(i)
+			style.Italic = !style.Italic
+		case CodeUnderline:
+			flush(i)
+			style.Underline = !style.Underline
+		case CodeStrikethrough:
+			flush(i)
+			style.Strikethrough = !style.Strikethrough
+		case CodeMonospace:
+			flush(i)
+			style.Monospace = !style.Monospace
+		case CodeReverse:
+			flush(i)
+			style.Reverse = !style.Reverse
+		case CodeReset:
+			flush(i)
+			style = Style{}
+		case CodeColor:
+			flush(i)
+
+			fg, fgLength := parseColorIndex(text[next:])
+			if fgLength == 0 {
+				style.Foreground = NoColor
+				style.Background = NoColor
+				break
+			}
+			style.Foreground = fg
+			next += fgLength
+
+			if next+1 < len(text) && text[next] == ',' {
+				if bg, bgLength := parseColorIndex(text[next+1:]); bgLength > 0 {
+					style.Background = bg
+					next += 1 + bgLength
+				}
+			}
+		case CodeHexColor:
+			flush(i)
+
+			fg, ok := parseColorHex(text[next:])
+			if !ok {
+				style.Foreground = NoColor
+				style.Background = NoColor
+				break
+			}
+			style.Foreground = fg
+			next += 6
+
+			if next+1 < len(text) && text[next] == ',' {
+				if bg, ok := parseColorHex(text[next+1:]); ok {
+					style.Background = bg
+					next += 7
+				}
+			}
+		default:
+			continue
+		}
+
+		start = next
+		i = next - 1
+	}
+
+	flush(len(text))
+
+	return spans
+}
+
+// Strip removes all formatting codes from the text.
+func Strip(text string) string {
+	return RenderPlain(Parse(text))
+}
+
+// parseColorIndex parses up to two digits at the start of s.
+func parseColorIndex(s string) (color Color, length int) {
+	for length < 2 && length < len(s) && s[length] >= '0' && s[length] <= '9' {
+		length++
+	}
+	if length == 0 {
+		return NoColor, 0
+	}
+
+	index, _ := strconv.Atoi(s[:length])
+	return IndexColor(index), length
+}
+
+// parseColorHex parses a six digit hex color at the start of s.
+func parseColorHex(s string) (color Color, ok bool) {
+	if len(s) < 6 {
+		return NoColor, false
+	}
+
+	value, err := strconv.ParseUint(s[:6], 16, 32)
+	if err != nil {
+		return NoColor, false
+	}
+
+	return RGBColor(uint8(value>>16), uint8(value>>8), uint8(value)), true
+}
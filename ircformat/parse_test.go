@@ -0,0 +1,129 @@
+package ircformat_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gissleh/irc/ircformat"
+)
+
+func TestParse(t *testing.T) {
+	table := []struct {
+		Text  string
+		Spans []ircformat.Span
+	}{
+		{"Hello, World", []ircformat.Span{
+			{Text: "Hello, World"},
+		}},
+		{"Hello, \x02World\x02!", []ircformat.Span{
+			{Text: "Hello, "},
+			{Style: ircformat.Style{Bold: true}, Text: "World"},
+			{Text: "!"},
+		}},
+		{"\x02\x1dBold italic\x0f plain", []ircformat.Span{
+			{Style: ircformat.Style{Bold: true, Italic: true}, Text: "Bold italic"},
+			{Text: " plain"},
+		}},
+		{"\x034red\x03 \x0312,08blue on yellow\x03,not a background", []ircformat.Span{
+			{Style: ircformat.Style{Foreground: ircformat.Red}, Text: "red"},
+			{Text: " "},
+			{Style: ircformat.Style{Foreground: ircformat.LightBlue, Background: ircformat.Yellow}, Text: "blue on yellow"},
+			{Text: ",not a background"},
+		}},
+		{"\x03044 apples", []ircformat.Span{
+			{Style: ircformat.Style{Foreground: ircformat.Red}, Text: "4 apples"},
+		}},
+		{"\x0304,text", []ircformat.Span{
+			{Style: ircformat.Style{Foreground: ircformat.Red}, Text: ",text"},
+		}},
+		{"\x04FF8800orange\x04 \x04112233,AABBCCboth", []ircformat.Span{
+			{Style: ircformat.Style{Foreground: ircformat.RGBColor(0xff, 0x88, 0x00)}, Text: "orange"},
+			{Text: " "},
+			{Style: ircformat.Style{Foreground: ircformat.RGBColor(0x11, 0x22, 0x33), Background: ircformat.RGBColor(0xaa, 0xbb, 0xcc)}, Text: "both"},
+		}},
+		{"\x1fund\x1e\x11\x16er", []ircformat.Span{
+			{Style: ircformat.Style{Underline: true}, Text: "und"},
+			{Style: ircformat.Style{Underline: true, Strikethrough: true, Monospace: true, Reverse: true}, Text: "er"},
+		}},
+		{"\x02\x02\x03", []ircformat.Span{}},
+	}
+
+	for _, row := range table {
+		t.Run(row.Text, func(t *testing.T) {
+			spans := ircformat.Parse(row.Text)
+			if !reflect.DeepEqual(spans, row.Spans) {
+				t.Errorf("Expected: %#+v", row.Spans)
+				t.Errorf("Result:   %#+v", spans)
+			}
+		})
+	}
+}
+
+func TestStrip(t *testing.T) {
+	text := "\x02Bold\x02 \x0304,12colored\x03 \x04FF0000hex\x04 \x0fdone"
+	if stripped := ircformat.Strip(text); stripped != "Bold colored hex done" {
+		t.Errorf("Result: %#+v", stripped)
+	}
+}
+
+func TestRender(t *testing.T) {
+	spans := ircformat.Parse("<b> \x02bold\x02 \x0304red\x03 \x0360ext")
+
+	if result := ircformat.RenderHTML(spans); result != `&lt;b&gt; <span style="font-weight:bold">bold</span> <span style="color:#ff0000">red</span> <span style="color:#0000ff">ext</span>` {
+		t.Errorf("HTML: %s", result)
+	}
+
+	if result := ircformat.RenderANSI(spans); result != "<b> \x1b[0;1mbold\x1b[0m \x1b[0;91mred\x1b[0m \x1b[0;38;2;0;0;255mext\x1b[0m" {
+		t.Errorf("ANSI: %#+v", result)
+	}
+
+	if result := ircformat.RenderPlain(spans); result != "<b> bold red ext" {
+		t.Errorf("Plain: %#+v", result)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	builder := ircformat.Builder{}
+	builder.Text("Score: ").
+		Bold("42").
+		Text(" ").
+		Color(ircformat.Green, ircformat.NoColor, "1st").
+		Text(" ").
+		Color(ircformat.RGBColor(0x12, 0x34, 0x56), ircformat.Black, "place")
+
+	expected := "Score: \x0242\x02 \x03031st\x03 \x04123456,000000place\x04"
+	if builder.String() != expected {
+		t.Errorf("Expected: %#+v", expected)
+		t.Errorf("Result:   %#+v", builder.String())
+	}
+
+	if stripped := ircformat.Strip(builder.String()); stripped != "Score: 42 1st place" {
+		t.Errorf("Round-trip failed: %#+v", stripped)
+	}
+
+	comma := ircformat.Builder{}
+	comma.Color(ircformat.Green, ircformat.NoColor, ",05 apples").
+		Color(ircformat.RGBColor(0x12, 0x34, 0x56), ircformat.NoColor, ",ABCDEF")
+	for _, span := range ircformat.Parse(comma.String()) {
+		if span.Text != "" && span.Style.Background.IsSet() {
+			t.Errorf("Text should not have a background: %#+v", span)
+		}
+	}
+	if stripped := ircformat.Strip(comma.String()); stripped != ",05 apples,ABCDEF" {
+		t.Errorf("Round-trip with comma failed: %#+v", stripped)
+	}
+
+	digits := ircformat.Builder{}
+	digits.Color(ircformat.Red, ircformat.NoColor, "red").
+		Text("5 apples ").
+		Color(ircformat.RGBColor(0x01, 0x02, 0x03), ircformat.NoColor, "x").
+		Text("ABCDEF text")
+	for _, span := range ircformat.Parse(digits.String()) {
+		if span.Text != "red" && span.Text != "x" && span.Style.Foreground.IsSet() {
+			t.Errorf("Text should not have a color: %#+v", span)
+		}
+	}
+	if stripped := ircformat.Strip(digits.String()); stripped != "red5 apples xABCDEF text" {
+		t.Errorf("Round-trip with digits failed: %#+v", stripped)
+	}
+}
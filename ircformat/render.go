@@ -0,0 +1,158 @@
+package ircformat
+
+import (
+	"bytes"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// ansiColors maps the 16 standard mIRC colors to the closest ANSI SGR foreground code. Add 10
+// to get the background code.
+var ansiColors = [16]int{97, 30, 34, 32, 91, 31, 35, 33, 93, 92, 36, 96, 94, 95, 90, 37}
+
+// RenderPlain renders the spans without any formatting.
+func RenderPlain(spans []Span) string {
+	buffer := bytes.Buffer{}
+	for _, span := range spans {
+		buffer.WriteString(span.Text)
+	}
+
+	return buffer.String()
+}
+
+// RenderANSI renders the spans with ANSI escape sequences for terminals. The standard colors
+// use the terminal's palette while the extended and hex colors use 24-bit color sequences.
+func RenderANSI(spans []Span) string {
+	buffer := bytes.Buffer{}
+	dirty := false
+
+	for _, span := range spans {
+		if span.Style.IsZero() {
+			if dirty {
+				buffer.WriteString("\x1b[0m")
+				dirty = false
+			}
+
+			buffer.WriteString(span.Text)
+			continue
+		}
+
+		codes := make([]string, 1, 8)
+		codes[0] = "0"
+		if span.Style.Bold {
+			codes = append(codes, "1")
+		}
+		if span.Style.Italic {
+			codes = append(codes, "3")
+		}
+		if span.Style.Underline {
+			codes = append(codes, "4")
+		}
+		if span.Style.Reverse {
+			codes = append(codes, "7")
+		}
+		if span.Style.Strikethrough {
+			codes = append(codes, "9")
+		}
+		if span.Style.Foreground.IsSet() {
+			codes = append(codes, ansiColor(span.Style.Foreground, false))
+		}
+		if span.Style.Background.IsSet() {
+			codes = append(codes, ansiColor(span.Style.Background, true))
+		}
+
+		buffer.WriteString("\x1b[" + strings.Join(codes, ";") + "m")
+		buffer.WriteString(span.Text)
+		dirty = true
+	}
+
+	if dirty {
+		buffer.WriteString("\x1b[0m")
+	}
+
+	return buffer.String()
+}
+
+// RenderHTML renders the spans as HTML. All text is escaped, and the only markup produced
+// are `<span>` elements with inline styles, so the result is safe to insert into a page.
+func RenderHTML(spans []Span) string {
+	buffer := bytes.Buffer{}
+
+	for _, span := range spans {
+		text := html.EscapeString(span.Text)
+		if span.Style.IsZero() {
+			buffer.WriteString(text)
+			continue
+		}
+
+		buffer.WriteString(`<span style="`)
+		buffer.WriteString(cssStyle(span.Style))
+		buffer.WriteString(`">`)
+		buffer.WriteString(text)
+		buffer.WriteString("</span>")
+	}
+
+	return buffer.String()
+}
+
+func ansiColor(color Color, background bool) string {
+	offset := 0
+	prefix := "38"
+	if background {
+		offset = 10
+		prefix = "48"
+	}
+
+	if index, ok := color.Index(); ok && index < len(ansiColors) {
+		return strconv.Itoa(ansiColors[index] + offset)
+	}
+
+	r, g, b := color.RGB()
+	return prefix + ";2;" + strconv.Itoa(int(r)) + ";" + strconv.Itoa(int(g)) + ";" + strconv.Itoa(int(b))
+}
+
+func cssStyle(style Style) string {
+	rules := make([]string, 0, 6)
+
+	if style.Bold {
+		rules = append(rules, "font-weight:bold")
+	}
+	if style.Italic {
+		rules = append(rules, "font-style:italic")
+	}
+	if style.Underline && style.Strikethrough {
+		rules = append(rules, "text-decoration:underline line-through")
+	} else if style.Underline {
+		rules = append(rules, "text-decoration:underline")
+	} else if style.Strikethrough {
+		rules = append(rules, "text-decoration:line-through")
+	}
+	if style.Monospace {
+		rules = append(rules, "font-family:monospace")
+	}
+
+	foreground := style.Foreground
+	background := style.Background
+	if style.Reverse {
+		// There's no way to know the page's colors, so assume black on white when reversing
+		// text with no colors set.
+		if !foreground.IsSet() {
+			foreground = Black
+		}
+		if !background.IsSet() {
+			background = White
+		}
+
+		foreground, background = background, foreground
+	}
+
+	if foreground.IsSet() {
+		rules = append(rules, "color:"+foreground.Hex())
+	}
+	if background.IsSet() {
+		rules = append(rules, "background-color:"+background.Hex())
+	}
+
+	return strings.Join(rules, ";")
+}
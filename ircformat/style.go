@@ -0,0 +1,146 @@
+// Package ircformat parses and produces the mIRC-style formatting codes used in IRC messages. Text
+// is parsed into spans of styled text that can be rendered for terminals, web pages or as plain
+// text.
+package ircformat
+
+import (
+	"fmt"
+)
+
+// Control codes used by mIRC-style formatting.
+const (
+	CodeBold          = '\x02'
+	CodeColor         = '\x03'
+	CodeHexColor      = '\x04'
+	CodeReset         = '\x0f'
+	CodeMonospace     = '\x11'
+	CodeReverse       = '\x16'
+	CodeItalic        = '\x1d'
+	CodeStrikethrough = '\x1e'
+	CodeUnderline     = '\x1f'
+)
+
+// A Color is either one of the 99 mIRC colors, an RGB color from the hex color code, or NoColor. The zero
+// value is NoColor, so an empty Style has no colors set.
+type Color uint32
+
+// NoColor means that the default color of the renderer should be used.
+const NoColor Color = 0
+
+const (
+	colorFlagIndex Color = 1 << 24
+	colorFlagRGB   Color = 1 << 25
+)
+
+// The 16 standard mIRC colors.
+const (
+	White Color = colorFlagIndex + iota
+	Black
+	Blue
+	Green
+	Red
+	Brown
+	Magenta
+	Orange
+	Yellow
+	LightGreen
+	Cyan
+	LightCyan
+	LightBlue
+	Pink
+	Grey
+	LightGrey
+)
+
+// palette contains the RGB values of all mIRC colors, including the extended colors from 16 to 98.
+var palette = [99]uint32{
+	0xffffff, 0x000000, 0x00007f, 0x009300, 0xff0000, 0x7f0000, 0x9c009c, 0xfc7f00,
+	0xffff00, 0x00fc00, 0x009393, 0x00ffff, 0x0000fc, 0xff00ff, 0x7f7f7f, 0xd2d2d2,
+	0x470000, 0x472100, 0x474700, 0x324700, 0x004700, 0x00472c, 0x004747, 0x002747,
+	0x000047, 0x2e0047, 0x470047, 0x47002a, 0x740000, 0x743a00, 0x747400, 0x517400,
+	0x007400, 0x007449, 0x007474, 0x004074, 0x000074, 0x4b0074, 0x740074, 0x740045,
+	0xb50000, 0xb56300, 0xb5b500, 0x7db500, 0x00b500, 0x00b571, 0x00b5b5, 0x0063b5,
+	0x0000b5, 0x7500b5, 0xb500b5, 0xb5006b, 0xff0000, 0xff8c00, 0xffff00, 0xb2ff00,
+	0x00ff00, 0x00ffa0, 0x00ffff, 0x008cff, 0x0000ff, 0xa500ff, 0xff00ff, 0xff0098,
+	0xff5959, 0xffb459, 0xffff71, 0xcfff60, 0x6fff6f, 0x65ffc9, 0x6dffff, 0x59b4ff,
+	0x5959ff, 0xc459ff, 0xff66ff, 0xff59bc, 0xff9c9c, 0xffd39c, 0xffff9c, 0xe2ff9c,
+	0x9cff9c, 0x9cffdb, 0x9cffff, 0x9cd3ff, 0x9c9cff, 0xdc9cff, 0xff9cff, 0xff94d3,
+	0x000000, 0x131313, 0x282828, 0x363636, 0x4d4d4d, 0x656565, 0x818181, 0x9f9f9f,
+	0xbcbcbc, 0xe2e2e2, 0xffffff,
+}
+
+// IndexColor gets the mIRC color by index. Any index outside 0-98 will give NoColor, which
+// is also what clients do with color 99.
+func IndexColor(index int) Color {
+	if index < 0 || index >= len(palette) {
+		return NoColor
+	}
+
+	return colorFlagIndex + Color(index)
+}
+
+// RGBColor gets a color by its red, green and blue components.
+func RGBColor(r, g, b uint8) Color {
+	return colorFlagRGB | Color(r)<<16 | Color(g)<<8 | Color(b)
+}
+
+// IsSet returns false if the color is NoColor.
+func (color Color) IsSet() bool {
+	return color&(colorFlagIndex|colorFlagRGB) != 0
+}
+
+// Index returns the mIRC color index, or false if it's an RGB color or NoColor.
+func (color Color) Index() (index int, ok bool) {
+	if color&colorFlagIndex == 0 {
+		return 0, false
+	}
+
+	return int(color &^ colorFlagIndex), true
+}
+
+// RGB gets the red, green and blue components of the color. mIRC colors are looked up in the
+// palette, while NoColor gives black.
+func (color Color) RGB() (r, g, b uint8) {
+	value := uint32(0)
+	if index, ok := color.Index(); ok {
+		value = palette[index]
+	} else if color&colorFlagRGB != 0 {
+		value = uint32(color & 0xffffff)
+	}
+
+	return uint8(value >> 16), uint8(value >> 8), uint8(value)
+}
+
+// Hex returns the color as a CSS-style hex string (e.g. "#ff0000"), or an empty string
+// if it's NoColor.
+func (color Color) Hex() string {
+	if !color.IsSet() {
+		return ""
+	}
+
+	r, g, b := color.RGB()
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+// Style is the formatting state that applies to a span of text.
+type Style struct {
+	Bold          bool  `json:"bold,omitempty"`
+	Italic        bool  `json:"italic,omitempty"`
+	Underline     bool  `json:"underline,omitempty"`
+	Strikethrough bool  `json:"strikethrough,omitempty"`
+	Monospace     bool  `json:"monospace,omitempty"`
+	Reverse       bool  `json:"reverse,omitempty"`
+	Foreground    Color `json:"foreground,omitempty"`
+	Background    Color `json:"background,omitempty"`
+}
+
+// IsZero returns true if no formatting is applied.
+func (style Style) IsZero() bool {
+	return style == Style{}
+}
+
+// A Span is a piece of text with the same style throughout.
+type Span struct {
+	Style Style  `json:"style"`
+	Text  string `json:"text"`
+}
@@ -0,0 +1,95 @@
+package ircutil
+
+import (
+	"net"
+	"strings"
+)
+
+// A BanMaskType is the kind of ban mask to make with BanMask.
+type BanMaskType int
+
+const (
+	// BanMaskHost bans everyone on the host, `*!*@host`.
+	BanMaskHost BanMaskType = iota
+	// BanMaskUserHost bans the user on the host, `*!*user@host`. The `~` marking an ident
+	// that's not verified is replaced by the leading `*`.
+	BanMaskUserHost
+	// BanMaskDomain bans everyone on the domain, `*!*@*.example.com`, or on the same /24 if the
+	// host is an IPv4 address, `*!*@10.0.0.*`. Hosts where that would be too broad, like IPv6
+	// addresses and cloaks, are banned like BanMaskHost.
+	BanMaskDomain
+	// BanMaskAccount bans the account with an extban, like `$a:account`.
+	BanMaskAccount
+)
+
+// BanMask makes a ban mask of the type for the user. The extban is the EXTBAN value from
+// ISUPPORT, which is only needed for BanMaskAccount. It returns false if a mask could not
+// be made, like if the server does not support account extbans or the user is not logged in.
+func BanMask(banType BanMaskType, hostmask Hostmask, account, extban string) (string, bool) {
+	switch banType {
+	case BanMaskHost:
+		if hostmask.Host == "" {
+			return "", false
+		}
+
+		return "*!*@" + hostmask.Host, true
+	case BanMaskUserHost:
+		if hostmask.Host == "" || hostmask.User == "" {
+			return "", false
+		}
+
+		return "*!*" + strings.TrimPrefix(hostmask.User, "~") + "@" + hostmask.Host, true
+	case BanMaskDomain:
+		if hostmask.Host == "" {
+			return "", false
+		}
+
+		return "*!*@" + domainWildcard(hostmask.Host), true
+	case BanMaskAccount:
+		if account == "" || account == "*" {
+			return "", false
+		}
+
+		prefix, ok := AccountExtban(extban)
+		if !ok {
+			return "", false
+		}
+
+		return prefix + account, true
+	}
+
+	return "", false
+}
+
+// AccountExtban gets the prefix of an account extban, like `$a:` or `~a:`, from the EXTBAN
+// value in ISUPPORT. It returns false if the server does not support them.
+func AccountExtban(extban string) (string, bool) {
+	comma := strings.IndexByte(extban, ',')
+	if comma == -1 {
+		return "", false
+	}
+
+	prefix, types := extban[:comma], extban[comma+1:]
+	if !strings.ContainsRune(types, 'a') {
+		return "", false
+	}
+
+	return prefix + "a:", true
+}
+
+func domainWildcard(host string) string {
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.To4() != nil {
+			return host[:strings.LastIndexByte(host, '.')+1] + "*"
+		}
+
+		return host
+	}
+
+	// Cloaks like `user/name` or `gateway/web/...` identify one user, not a domain.
+	if strings.ContainsAny(host, "/:") || strings.Count(host, ".") < 2 {
+		return host
+	}
+
+	return "*" + host[strings.IndexByte(host, '.'):]
+}
@@ -0,0 +1,68 @@
+package ircutil_test
+
+import (
+	"testing"
+
+	"github.com/gissleh/irc/ircutil"
+)
+
+func TestParseHostmask(t *testing.T) {
+	table := []struct {
+		S      string
+		Result ircutil.Hostmask
+		String string
+	}{
+		{"Test!~test@example.com", ircutil.Hostmask{Nick: "Test", User: "~test", Host: "example.com"}, "Test!~test@example.com"},
+		{"Test", ircutil.Hostmask{Nick: "Test"}, "Test!*@*"},
+		{"Test@example.com", ircutil.Hostmask{Nick: "Test", Host: "example.com"}, "Test!*@example.com"},
+		{"irc.example.com", ircutil.Hostmask{Nick: "irc.example.com"}, "irc.example.com!*@*"},
+	}
+
+	for _, row := range table {
+		result := ircutil.ParseHostmask(row.S)
+		if result != row.Result {
+			t.Errorf("ParseHostmask(%q) = %#+v", row.S, result)
+		}
+		if result.String() != row.String {
+			t.Errorf("ParseHostmask(%q).String() = %q", row.S, result.String())
+		}
+	}
+}
+
+func TestBanMask(t *testing.T) {
+	user := ircutil.ParseHostmask("Test!~test@host-1.dsl.example.com")
+
+	table := []struct {
+		Type     ircutil.BanMaskType
+		Hostmask ircutil.Hostmask
+		Account  string
+		Extban   string
+		Result   string
+		OK       bool
+	}{
+		{ircutil.BanMaskHost, user, "", "", "*!*@host-1.dsl.example.com", true},
+		{ircutil.BanMaskUserHost, user, "", "", "*!*test@host-1.dsl.example.com", true},
+		{ircutil.BanMaskDomain, user, "", "", "*!*@*.dsl.example.com", true},
+		{ircutil.BanMaskDomain, ircutil.ParseHostmask("Test!test@10.32.0.1"), "", "", "*!*@10.32.0.*", true},
+		{ircutil.BanMaskDomain, ircutil.ParseHostmask("Test!test@2001:db8::1"), "", "", "*!*@2001:db8::1", true},
+		{ircutil.BanMaskDomain, ircutil.ParseHostmask("Test!test@user/test"), "", "", "*!*@user/test", true},
+		{ircutil.BanMaskDomain, ircutil.ParseHostmask("Test!test@example.com"), "", "", "*!*@example.com", true},
+		{ircutil.BanMaskAccount, user, "test", "$,&acjmorsuxz|", "$a:test", true},
+		{ircutil.BanMaskAccount, user, "test", "~,qjncrRa", "~a:test", true},
+		{ircutil.BanMaskAccount, user, "test", "~,qjncrR", "", false},
+		{ircutil.BanMaskAccount, user, "test", "", "", false},
+		{ircutil.BanMaskAccount, user, "*", "$,&acjmorsuxz|", "", false},
+		{ircutil.BanMaskHost, ircutil.ParseHostmask("Test"), "", "", "", false},
+	}
+
+	for _, row := range table {
+		result, ok := ircutil.BanMask(row.Type, row.Hostmask, row.Account, row.Extban)
+		if result != row.Result || ok != row.OK {
+			t.Errorf("BanMask(%d, %s, %q, %q) = %q, %t", row.Type, row.Hostmask, row.Account, row.Extban, result, ok)
+		}
+
+		if ok && row.Type != ircutil.BanMaskAccount && !row.Hostmask.Matches(result, "rfc1459") {
+			t.Errorf("%s does not match its own ban mask %s", row.Hostmask, result)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package ircutil
+
+import (
+	"strings"
+)
+
+// A Hostmask is the `nick!user@host` identifying a user.
+type Hostmask struct {
+	Nick string
+	User string
+	Host string
+}
+
+// ParseHostmask parses a `nick!user@host` string. The parts that are missing are left empty,
+// so a server name or lone nick is parsed into only the Nick.
+func ParseHostmask(s string) Hostmask {
+	hostmask := Hostmask{}
+
+	if at := strings.LastIndexByte(s, '@'); at != -1 {
+		hostmask.Host = s[at+1:]
+		s = s[:at]
+	}
+	if exclamation := strings.IndexByte(s, '!'); exclamation != -1 {
+		hostmask.User = s[exclamation+1:]
+		s = s[:exclamation]
+	}
+	hostmask.Nick = s
+
+	return hostmask
+}
+
+// String returns the hostmask as `nick!user@host`. Missing parts are replaced by `*`.
+func (hostmask Hostmask) String() string {
+	return orStar(hostmask.Nick) + "!" + orStar(hostmask.User) + "@" + orStar(hostmask.Host)
+}
+
+// Matches returns true if the hostmask matches the mask, see MatchMask.
+func (hostmask Hostmask) Matches(mask, casemapping string) bool {
+	return MatchMask(mask, hostmask.String(), casemapping)
+}
+
+func orStar(s string) string {
+	if s == "" {
+		return "*"
+	}
+
+	return s
+}
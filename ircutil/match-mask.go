@@ -0,0 +1,116 @@
+package ircutil
+
+import (
+	"strings"
+)
+
+// CaseFold lowercases a nick, channel or mask using the CASEMAPPING from ISUPPORT. The
+// casemappings `ascii`, `rfc1459` and `rfc1459-strict` are supported, and anything else
+// is treated as `rfc1459`, which is the default in the RFC.
+func CaseFold(s, casemapping string) string {
+	upperEnd := caseMappingEnd(casemapping)
+
+	var result []byte
+	for i := 0; i < len(s); i++ {
+		if c := foldByte(s[i], upperEnd); c != s[i] {
+			if result == nil {
+				result = []byte(s)
+			}
+
+			result[i] = c
+		}
+	}
+	if result == nil {
+		return s
+	}
+
+	return string(result)
+}
+
+// MatchMask returns true if the string matches the mask, ignoring case according to the
+// casemapping (see CaseFold). In the mask, `*` matches any number of characters and `?`
+// matches exactly one. A backslash escapes the character after it, so `\*` only matches
+// a `*`.
+func MatchMask(mask, s, casemapping string) bool {
+	upperEnd := caseMappingEnd(casemapping)
+	mi, si := 0, 0
+	starMi, starSi := -1, -1
+
+	for si < len(s) {
+		if mi < len(mask) {
+			c := mask[mi]
+			switch {
+			case c == '*':
+				mi++
+				starMi, starSi = mi, si
+				continue
+			case c == '?':
+				mi++
+				si++
+				continue
+			case c == '\\' && mi+1 < len(mask):
+				if foldByte(mask[mi+1], upperEnd) == foldByte(s[si], upperEnd) {
+					mi += 2
+					si++
+					continue
+				}
+			case foldByte(c, upperEnd) == foldByte(s[si], upperEnd):
+				mi++
+				si++
+				continue
+			}
+		}
+
+		// Backtrack to the last star, and let it eat one more character.
+		if starMi == -1 {
+			return false
+		}
+		starSi++
+		mi, si = starMi, starSi
+	}
+
+	for mi < len(mask) && mask[mi] == '*' {
+		mi++
+	}
+
+	return mi == len(mask)
+}
+
+// EscapeMask escapes the characters that MatchMask treats as special, so that a nick or other
+// literal string can be put in a mask and only match itself.
+func EscapeMask(s string) string {
+	if !strings.ContainsAny(s, "*?\\") {
+		return s
+	}
+
+	result := make([]byte, 0, len(s)+2)
+	for i := 0; i < len(s); i++ {
+		if s[i] == '*' || s[i] == '?' || s[i] == '\\' {
+			result = append(result, '\\')
+		}
+		result = append(result, s[i])
+	}
+
+	return string(result)
+}
+
+// caseMappingEnd gets the last uppercase character of the casemapping. The lowercase version of each
+// character from `A` to it is 32 higher.
+func caseMappingEnd(casemapping string) byte {
+	switch strings.ToLower(casemapping) {
+	case "ascii":
+		return 'Z'
+	case "rfc1459-strict", "strict-rfc1459":
+		return ']'
+	default:
+		return '^'
+	}
+}
+
+func foldByte(c, upperEnd byte) byte {
+	if c >= 'A' && c <= upperEnd {
+		return c + ('a' - 'A')
+	}
+
+	return c
+}
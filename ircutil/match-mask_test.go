@@ -0,0 +1,86 @@
+package ircutil_test
+
+import (
+	"testing"
+
+	"github.com/gissleh/irc/ircutil"
+)
+
+func TestMatchMask(t *testing.T) {
+	table := []struct {
+		Mask        string
+		S           string
+		Casemapping string
+		Result      bool
+	}{
+		{"*!*@*", "Test!~test@example.com", "", true},
+		{"*!*@example.com", "Test!~test@EXAMPLE.com", "", true},
+		{"*!*@example.com", "Test!~test@example.org", "", false},
+		{"Te?t!*@*", "Test!~test@example.com", "", true},
+		{"Te?t!*@*", "Tet!~test@example.com", "", false},
+		{"*!*test@*.example.com", "Test!~test@host.example.com", "", true},
+		{"*!*test@*.example.com", "Test!~test@example.com", "", false},
+		{"*a*b*c", "xxaxxbxxbxxc", "", true},
+		{"*a*b*c", "xxaxxbxxbxxcx", "", false},
+		{"**", "", "", true},
+		{"?", "", "", false},
+		{"\\*!*@*", "*!x@y", "", true},
+		{"\\*!*@*", "a!x@y", "", false},
+		{"a\\?b", "a?b", "", true},
+		{"a\\?b", "axb", "", false},
+		{"a\\\\b", "a\\b", "", true},
+		{"[Test]!*@*", "{test}!~test@example.com", "rfc1459", true},
+		{"[Test]!*@*", "{test}!~test@example.com", "ascii", false},
+		{"Test^!*@*", "test~!~test@example.com", "rfc1459", true},
+		{"Test^!*@*", "test~!~test@example.com", "rfc1459-strict", false},
+	}
+
+	for _, row := range table {
+		t.Run(row.Mask+" "+row.S, func(t *testing.T) {
+			if result := ircutil.MatchMask(row.Mask, row.S, row.Casemapping); result != row.Result {
+				t.Errorf("MatchMask(%q, %q, %q) = %t", row.Mask, row.S, row.Casemapping, result)
+			}
+		})
+	}
+}
+
+func TestEscapeMask(t *testing.T) {
+	table := []struct {
+		S      string
+		Result string
+	}{
+		{"Test", "Test"},
+		{"foo\\bar", "foo\\\\bar"},
+		{"a*b?c", "a\\*b\\?c"},
+	}
+
+	for _, row := range table {
+		result := ircutil.EscapeMask(row.S)
+		if result != row.Result {
+			t.Errorf("EscapeMask(%q) = %q", row.S, result)
+		}
+		if !ircutil.MatchMask(result, row.S, "ascii") || ircutil.MatchMask(result, row.S+"x", "ascii") {
+			t.Errorf("EscapeMask(%q) should only match itself", row.S)
+		}
+	}
+}
+
+func TestCaseFold(t *testing.T) {
+	table := []struct {
+		S           string
+		Casemapping string
+		Result      string
+	}{
+		{"Test[]\\^", "", "test{}|~"},
+		{"Test[]\\^", "rfc1459", "test{}|~"},
+		{"Test[]\\^", "rfc1459-strict", "test{}|^"},
+		{"Test[]\\^", "ascii", "test[]\\^"},
+		{"lower", "rfc1459", "lower"},
+	}
+
+	for _, row := range table {
+		if result := ircutil.CaseFold(row.S, row.Casemapping); result != row.Result {
+			t.Errorf("CaseFold(%q, %q) = %q", row.S, row.Casemapping, result)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package isupport
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A ChanLimit is one entry in CHANLIMIT. The limit is shared between all the channel prefixes
+// in the entry.
+type ChanLimit struct {
+	Prefixes string
+	Limit    int
+}
+
+// NickLen gets the max length of a nick, which is 9 if the server doesn't say otherwise.
+func (isupport *ISupport) NickLen() int {
+	return isupport.numberOrDefault("NICKLEN", 9)
+}
+
+// ChannelLen gets the max length of a channel name, which is 200 if the server doesn't say otherwise.
+func (isupport *ISupport) ChannelLen() int {
+	return isupport.numberOrDefault("CHANNELLEN", 200)
+}
+
+// TopicLen gets the max length of a topic, or 0 if there is no limit.
+func (isupport *ISupport) TopicLen() int {
+	return isupport.numberOrDefault("TOPICLEN", 0)
+}
+
+// KickLen gets the max length of a kick reason, or 0 if there is no limit.
+func (isupport *ISupport) KickLen() int {
+	return isupport.numberOrDefault("KICKLEN", 0)
+}
+
+// AwayLen gets the max length of an away message, or 0 if there is no limit.
+func (isupport *ISupport) AwayLen() int {
+	return isupport.numberOrDefault("AWAYLEN", 0)
+}
+
+// Modes gets how many modes with an argument can be changed in one MODE command. It is 3
+// if the server doesn't say otherwise, and 0 if there is no limit.
+func (isupport *ISupport) Modes() int {
+	return isupport.numberOrDefault("MODES", 3)
+}
+
+// MaxList gets how many entries the list mode (like `b` for bans) can have, or 0 if it's unknown.
+func (isupport *ISupport) MaxList(mode rune) int {
+	value, _ := isupport.Get("MAXLIST")
+
+	for _, token := range strings.Split(value, ",") {
+		colon := strings.IndexByte(token, ':')
+		if colon == -1 || !strings.ContainsRune(token[:colon], mode) {
+			continue
+		}
+
+		limit, _ := strconv.Atoi(token[colon+1:])
+		return limit
+	}
+
+	return 0
+}
+
+// ChanLimits gets the CHANLIMIT entries. A limit of 0 means that there is no limit.
+func (isupport *ISupport) ChanLimits() []ChanLimit {
+	value, ok := isupport.Get("CHANLIMIT")
+	if !ok || value == "" {
+		return nil
+	}
+
+	limits := make([]ChanLimit, 0, 2)
+	for _, token := range strings.Split(value, ",") {
+		colon := strings.IndexByte(token, ':')
+		if colon == -1 {
+			continue
+		}
+
+		limit, _ := strconv.Atoi(token[colon+1:])
+		limits = append(limits, ChanLimit{Prefixes: token[:colon], Limit: limit})
+	}
+
+	return limits
+}
+
+// ChanLimit gets how many channels with the prefix can be joined. It returns false if the
+// server does not say, and 0 if there is no limit. The limit may be shared with other
+// prefixes, see ChanLimits.
+func (isupport *ISupport) ChanLimit(prefix rune) (int, bool) {
+	for _, limit := range isupport.ChanLimits() {
+		if strings.ContainsRune(limit.Prefixes, prefix) {
+			return limit.Limit, true
+		}
+	}
+
+	return 0, false
+}
+
+// TargMax gets how many targets the command can have. It returns false if the server does not
+// say, and 0 if there is no limit.
+func (isupport *ISupport) TargMax(command string) (int, bool) {
+	value, ok := isupport.Get("TARGMAX")
+	if !ok {
+		return 0, false
+	}
+
+	for _, token := range strings.Split(value, ",") {
+		if colon := strings.IndexByte(token, ':'); colon != -1 && strings.EqualFold(token[:colon], command) {
+			limit, _ := strconv.Atoi(token[colon+1:])
+			return limit, true
+		}
+	}
+
+	return 0, false
+}
+
+// StatusMsg gets the prefixes that can be put in front of a channel name to only message the
+// users with that prefix or higher, like `@#Channel`.
+func (isupport *ISupport) StatusMsg() string {
+	value, _ := isupport.Get("STATUSMSG")
+	return value
+}
+
+// Network gets the network name, or an empty string if the server doesn't say.
+func (isupport *ISupport) Network() string {
+	value, _ := isupport.Get("NETWORK")
+	return value
+}
+
+// EList gets the LIST extensions as upper case letters, like `CMNTU`.
+func (isupport *ISupport) EList() string {
+	value, _ := isupport.Get("ELIST")
+	return strings.ToUpper(value)
+}
+
+// SafeList returns true if LIST will not get the client disconnected for flooding.
+func (isupport *ISupport) SafeList() bool {
+	_, ok := isupport.Get("SAFELIST")
+	return ok
+}
+
+// CaseMapping gets the casemapping used for nicks and channel names, which is `rfc1459` if the
+// server doesn't say otherwise.
+func (isupport *ISupport) CaseMapping() string {
+	value, ok := isupport.Get("CASEMAPPING")
+	if !ok || value == "" {
+		return "rfc1459"
+	}
+
+	return value
+}
+
+// numberOrDefault gets a number, or the default if it's missing or invalid. If the key is set
+// without a value, it's 0 for no limit.
+func (isupport *ISupport) numberOrDefault(key string, defaultValue int) int {
+	value, ok := isupport.Get(key)
+	if !ok {
+		return defaultValue
+	}
+	if value == "" {
+		return 0
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return defaultValue
+	}
+
+	return number
+}
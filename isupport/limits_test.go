@@ -0,0 +1,85 @@
+package isupport_test
+
+import (
+	"testing"
+
+	"github.com/gissleh/irc/isupport"
+)
+
+func TestISupport_Limits(t *testing.T) {
+	assertEq(t, 30, is.NickLen(), "NickLen")
+	assertEq(t, 50, is.ChannelLen(), "ChannelLen")
+	assertEq(t, 390, is.TopicLen(), "TopicLen")
+	assertEq(t, 0, is.KickLen(), "KickLen")
+	assertEq(t, 0, is.AwayLen(), "AwayLen")
+	assertEq(t, 4, is.Modes(), "Modes")
+	assertEq(t, 100, is.MaxList('b'), "MaxList b")
+	assertEq(t, 100, is.MaxList('I'), "MaxList I")
+	assertEq(t, 0, is.MaxList('x'), "MaxList x")
+	assertEq(t, "@+%", is.StatusMsg(), "StatusMsg")
+	assertEq(t, "TestServer", is.Network(), "Network")
+	assertEq(t, "CTU", is.EList(), "EList")
+	assertEq(t, true, is.SafeList(), "SafeList")
+	assertEq(t, "rfc1459", is.CaseMapping(), "CaseMapping")
+}
+
+func TestISupport_Defaults(t *testing.T) {
+	empty := isupport.ISupport{}
+
+	assertEq(t, 9, empty.NickLen(), "NickLen")
+	assertEq(t, 200, empty.ChannelLen(), "ChannelLen")
+	assertEq(t, 0, empty.TopicLen(), "TopicLen")
+	assertEq(t, 3, empty.Modes(), "Modes")
+	assertEq(t, 0, empty.MaxList('b'), "MaxList")
+	assertEq(t, []isupport.ChanLimit(nil), empty.ChanLimits(), "ChanLimits")
+	assertEq(t, "", empty.StatusMsg(), "StatusMsg")
+	assertEq(t, false, empty.SafeList(), "SafeList")
+	assertEq(t, "rfc1459", empty.CaseMapping(), "CaseMapping")
+
+	targMax, ok := empty.TargMax("PRIVMSG")
+	assertEq(t, 0, targMax, "TargMax")
+	assertEq(t, false, ok, "TargMax ok")
+
+	empty.Set("MODES", "")
+	assertEq(t, 0, empty.Modes(), "Modes without limit")
+}
+
+func TestISupport_ChanLimit(t *testing.T) {
+	assertEq(t, []isupport.ChanLimit{{Prefixes: "#&", Limit: 15}}, is.ChanLimits(), "ChanLimits")
+
+	table := []struct {
+		Prefix rune
+		Limit  int
+		OK     bool
+	}{
+		{'#', 15, true},
+		{'&', 15, true},
+		{'+', 0, false},
+	}
+
+	for _, row := range table {
+		limit, ok := is.ChanLimit(row.Prefix)
+		assertEq(t, row.Limit, limit, "limit for "+string(row.Prefix))
+		assertEq(t, row.OK, ok, "ok for "+string(row.Prefix))
+	}
+}
+
+func TestISupport_TargMax(t *testing.T) {
+	table := []struct {
+		Command string
+		Limit   int
+		OK      bool
+	}{
+		{"PRIVMSG", 4, true},
+		{"notice", 4, true},
+		{"KICK", 1, true},
+		{"ACCEPT", 0, true},
+		{"JOIN", 0, false},
+	}
+
+	for _, row := range table {
+		limit, ok := is.TargMax(row.Command)
+		assertEq(t, row.Limit, limit, "limit for "+row.Command)
+		assertEq(t, row.OK, ok, "ok for "+row.Command)
+	}
+}
@@ -0,0 +1,289 @@
+package irc
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrClientNotFound is returned by Manager functions when the client ID is unknown.
+var ErrClientNotFound = errors.New("irc: client not found")
+
+// ErrClientAlreadyAdded is returned by Manager.Add if a client with the same ID has been added.
+var ErrClientAlreadyAdded = errors.New("irc: client already added")
+
+// A ManagerEvent is an event from one of the manager's clients.
+type ManagerEvent struct {
+	ClientID string
+	Event    *Event
+}
+
+// A Manager owns multiple clients, like one per network, and lets them be handled as one.
+type Manager struct {
+	mutex   sync.RWMutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	clients map[string]*managedClient
+	routes  []*managerRoute
+
+	events        chan ManagerEvent
+	eventsEnabled bool
+}
+
+type managedClient struct {
+	client  *Client
+	addr    string
+	ssl     bool
+	handles map[*managerRoute]HandlerHandle
+	forward HandlerHandle
+}
+
+type managerRoute struct {
+	pattern  string
+	priority int
+	handler  Handler
+}
+
+// NewManager creates a manager. The clients it creates will be destroyed along with the context.
+func NewManager(ctx context.Context) *Manager {
+	manager := &Manager{
+		clients: make(map[string]*managedClient, 16),
+		events:  make(chan ManagerEvent, 256),
+	}
+	manager.ctx, manager.cancel = context.WithCancel(ctx)
+
+	return manager
+}
+
+// Context gets the manager's context, which is cancelled by DestroyAll.
+func (manager *Manager) Context() context.Context {
+	return manager.ctx
+}
+
+// New creates a client with the manager's context and adds it. The addr and ssl is used
+// by ConnectAll.
+func (manager *Manager) New(config Config, addr string, ssl bool) *Client {
+	client := New(manager.ctx, config)
+	_ = manager.Add(client, addr, ssl)
+
+	return client
+}
+
+// Add adds a client to the manager under its ID, and adds the global handlers to it. The addr and ssl
+// is used by ConnectAll.
+func (manager *Manager) Add(client *Client, addr string, ssl bool) error {
+	id := client.ID()
+
+	manager.mutex.Lock()
+	if _, ok := manager.clients[id]; ok {
+		manager.mutex.Unlock()
+		return ErrClientAlreadyAdded
+	}
+	managed := &managedClient{
+		client:  client,
+		addr:    addr,
+		ssl:     ssl,
+		handles: make(map[*managerRoute]HandlerHandle, len(manager.routes)),
+	}
+	for _, r := range manager.routes {
+		managed.handles[r] = client.OnPriority(r.pattern, r.priority, r.handler)
+	}
+	managed.forward = client.AddHandler(manager.forwardEvent)
+	manager.clients[id] = managed
+	manager.mutex.Unlock()
+
+	return nil
+}
+
+// Remove removes the client from the manager without destroying it. The global handlers are still
+// attached to it, but its events will no longer be in Events and removing the global handlers
+// will not affect it.
+func (manager *Manager) Remove(id string) (*Client, error) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	managed, ok := manager.clients[id]
+	if !ok {
+		return nil, ErrClientNotFound
+	}
+	delete(manager.clients, id)
+	managed.forward.Remove()
+
+	return managed.client, nil
+}
+
+// Client gets a client by ID, or nil if it is not found.
+func (manager *Manager) Client(id string) *Client {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	if managed, ok := manager.clients[id]; ok {
+		return managed.client
+	}
+
+	return nil
+}
+
+// Clients gets all clients, sorted by ID.
+func (manager *Manager) Clients() []*Client {
+	manager.mutex.RLock()
+	clients := make([]*Client, 0, len(manager.clients))
+	for _, managed := range manager.clients {
+		clients = append(clients, managed.client)
+	}
+	manager.mutex.RUnlock()
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].ID() < clients[j].ID()
+	})
+
+	return clients
+}
+
+// AddHandler adds a handler for all events to all current and future clients.
+func (manager *Manager) AddHandler(handler Handler) HandlerHandle {
+	return manager.OnPriority("*", 0, handler)
+}
+
+// On adds a handler for the pattern to all current and future clients. See Client.On for
+// the patterns.
+func (manager *Manager) On(pattern string, handler Handler) HandlerHandle {
+	return manager.OnPriority(pattern, 0, handler)
+}
+
+// OnPriority adds a handler for the pattern with a priority to all current and future clients.
+// See Client.OnPriority for how priorities work. Removing it will remove it from all clients.
+func (manager *Manager) OnPriority(pattern string, priority int, handler Handler) HandlerHandle {
+	r := &managerRoute{pattern: pattern, priority: priority, handler: handler}
+
+	manager.mutex.Lock()
+	manager.routes = append(manager.routes[:len(manager.routes):len(manager.routes)], r)
+	for _, managed := range manager.clients {
+		managed.handles[r] = managed.client.OnPriority(pattern, priority, handler)
+	}
+	manager.mutex.Unlock()
+
+	return HandlerHandle{remove: func() { manager.removeRoute(r) }}
+}
+
+func (manager *Manager) removeRoute(r *managerRoute) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	for i := range manager.routes {
+		if manager.routes[i] == r {
+			manager.routes = append(manager.routes[:i:i], manager.routes[i+1:]...)
+			break
+		}
+	}
+
+	for _, managed := range manager.clients {
+		if handle, ok := managed.handles[r]; ok {
+			handle.Remove()
+			delete(managed.handles, r)
+		}
+	}
+}
+
+// Events gets a channel with copies of every event from every client. Nothing is sent on it until
+// this is called the first time. The events are dropped if the consumer falls behind and the buffer
+// of 256 events is full, so that a slow consumer cannot block the clients' event loops. It is never
+// closed, so the consumer should also watch the manager's context.
+func (manager *Manager) Events() <-chan ManagerEvent {
+	manager.mutex.Lock()
+	manager.eventsEnabled = true
+	manager.mutex.Unlock()
+
+	return manager.events
+}
+
+// ConnectAll connects all clients that are not connected to their addresses in parallel, and
+// returns the errors by client ID. The map is empty if all connections succeeded.
+func (manager *Manager) ConnectAll() map[string]error {
+	manager.mutex.RLock()
+	managedClients := make([]*managedClient, 0, len(manager.clients))
+	for _, managed := range manager.clients {
+		managedClients = append(managedClients, managed)
+	}
+	manager.mutex.RUnlock()
+
+	errs := make(map[string]error)
+	errMutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	for _, managed := range managedClients {
+		if managed.client.Connected() {
+			continue
+		}
+
+		wg.Add(1)
+		go func(managed *managedClient) {
+			defer wg.Done()
+
+			if err := managed.client.Connect(managed.addr, managed.ssl); err != nil {
+				errMutex.Lock()
+				errs[managed.client.ID()] = err
+				errMutex.Unlock()
+			}
+		}(managed)
+	}
+
+	wg.Wait()
+
+	return errs
+}
+
+// QuitAll quits all connected clients with the reason.
+func (manager *Manager) QuitAll(reason string) {
+	for _, client := range manager.Clients() {
+		if client.Connected() {
+			client.Quit(reason)
+		}
+	}
+}
+
+// DestroyAll destroys and removes all clients. The manager's context is cancelled, so it cannot
+// be used afterwards.
+func (manager *Manager) DestroyAll() {
+	manager.mutex.Lock()
+	clients := manager.clients
+	manager.clients = make(map[string]*managedClient)
+	manager.mutex.Unlock()
+
+	for _, managed := range clients {
+		managed.client.Destroy()
+	}
+
+	manager.cancel()
+}
+
+// State gets the state of every client by ID.
+func (manager *Manager) State() map[string]ClientState {
+	clients := manager.Clients()
+
+	states := make(map[string]ClientState, len(clients))
+	for _, client := range clients {
+		states[client.ID()] = client.State()
+	}
+
+	return states
+}
+
+func (manager *Manager) forwardEvent(event *Event, client *Client) {
+	id := client.ID()
+
+	manager.mutex.RLock()
+	managed := manager.clients[id]
+	enabled := manager.eventsEnabled
+	manager.mutex.RUnlock()
+
+	if !enabled || managed == nil || managed.client != client {
+		return
+	}
+
+	select {
+	case manager.events <- ManagerEvent{ClientID: id, Event: event.Copy()}:
+	default:
+	}
+}
@@ -0,0 +1,132 @@
+package irc_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/internal/irctest"
+)
+
+func TestManager(t *testing.T) {
+	manager := irc.NewManager(context.Background())
+
+	handledMutex := sync.Mutex{}
+	handled := make(map[string]int)
+	manager.AddHandler(func(event *irc.Event, client *irc.Client) {
+		if event.Name() == "packet.privmsg" {
+			handledMutex.Lock()
+			handled[client.ID()]++
+			handledMutex.Unlock()
+		}
+	})
+
+	interactions := make([]*irctest.Interaction, 0, 2)
+	clients := make([]*irc.Client, 0, 2)
+	for _, nick := range []string{"Test", "Test2"} {
+		interaction := &irctest.Interaction{
+			Lines: append(irctest.Registration(nick, "Tester", "..."),
+				irctest.InteractionLine{Server: ":Gisle!~irce@10.32.0.1 PRIVMSG " + nick + " :Hello, " + nick},
+				irctest.InteractionLine{Server: "PING :testserver.example.com"},
+				irctest.InteractionLine{Client: "PONG :testserver.example.com"},
+			),
+		}
+
+		addr, err := interaction.Listen()
+		if err != nil {
+			t.Fatal("Listen:", err)
+		}
+
+		clients = append(clients, manager.New(irc.Config{
+			Nick:     nick,
+			User:     "Tester",
+			RealName: "...",
+			SendRate: 1000,
+		}, addr, false))
+		interactions = append(interactions, interaction)
+	}
+
+	messages := make(map[string]string)
+	done := make(chan struct{})
+	events := manager.Events()
+	go func() {
+		defer close(done)
+
+		for len(messages) < 2 {
+			select {
+			case managerEvent := <-events:
+				if managerEvent.Event.Name() == "packet.privmsg" {
+					messages[managerEvent.ClientID] = managerEvent.Event.Text
+				}
+			case <-time.After(time.Second * 5):
+				return
+			}
+		}
+	}()
+
+	if errs := manager.ConnectAll(); len(errs) > 0 {
+		t.Fatal("ConnectAll:", errs)
+	}
+	for i, interaction := range interactions {
+		interaction.Wait()
+		if interaction.Failure != nil {
+			t.Fatalf("Interaction %d failed: %#+v", i, interaction.Failure)
+		}
+	}
+	<-done
+
+	for i, nick := range []string{"Test", "Test2"} {
+		id := clients[i].ID()
+		if messages[id] != "Hello, "+nick {
+			t.Errorf("Wrong message for %s: %#+v", nick, messages[id])
+		}
+
+		handledMutex.Lock()
+		count := handled[id]
+		handledMutex.Unlock()
+		if count != 1 {
+			t.Errorf("Global handler should have handled one message for %s, got %d", nick, count)
+		}
+	}
+
+	states := manager.State()
+	if len(states) != 2 || states[clients[1].ID()].Nick != "Test2" {
+		t.Errorf("Wrong states: %#+v", states)
+	}
+
+	if client, err := manager.Remove(clients[0].ID()); err != nil || client != clients[0] {
+		t.Error("Remove should return the removed client")
+	}
+	if manager.Client(clients[0].ID()) != nil || len(manager.Clients()) != 1 {
+		t.Error("Client should be removed")
+	}
+	if _, err := manager.Remove(clients[0].ID()); err != irc.ErrClientNotFound {
+		t.Error("Removing again should fail, got", err)
+	}
+	if err := manager.Add(clients[1], "", false); err != irc.ErrClientAlreadyAdded {
+		t.Error("Adding again should fail, got", err)
+	}
+
+	// The event should only be forwarded once after the client is removed and added again.
+	if err := manager.Add(clients[0], "", false); err != nil {
+		t.Fatal("Adding the removed client should work, got", err)
+	}
+	<-clients[0].Emit(irc.NewEvent("test", "forward")).Done()
+	forwarded := 0
+	timeout := time.After(time.Millisecond * 100)
+	for waiting := true; waiting; {
+		select {
+		case managerEvent := <-events:
+			if managerEvent.Event.Name() == "test.forward" {
+				forwarded++
+			}
+		case <-timeout:
+			waiting = false
+		}
+	}
+	if forwarded != 1 {
+		t.Errorf("Event should be forwarded once, got %d", forwarded)
+	}
+}
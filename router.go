@@ -0,0 +1,145 @@
+package irc
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A HandlerHandle is returned when adding a handler, and can be used to remove it again.
+type HandlerHandle struct {
+	remove func()
+}
+
+// Remove removes the handler. It takes effect from the next event, and calling it more than
+// once does nothing.
+func (handle HandlerHandle) Remove() {
+	if handle.remove != nil {
+		handle.remove()
+	}
+}
+
+// routerCacheSize is how many event names the router caches the handlers for. The names come from
+// the server, so there's no limit to how many there can be.
+const routerCacheSize = 256
+
+// A router dispatches events to the handlers whose pattern matches the event name. The handlers
+// for each name are sorted and cached on first use, so the dispatch is just a map lookup. The
+// cache is cleared when it's full.
+type router struct {
+	mutex     sync.RWMutex
+	routes    []*route
+	nextOrder int
+	cache     map[string][]*route
+}
+
+type route struct {
+	pattern  string
+	priority int
+	order    int
+	handler  Handler
+}
+
+func newRouter() *router {
+	return &router{cache: make(map[string][]*route, 64)}
+}
+
+// add adds a handler for the pattern, which is either an exact event name (`packet.privmsg`),
+// all events of a kind (`ctcp.*`) or all events (`*`).
+func (router *router) add(pattern string, priority int, handler Handler) HandlerHandle {
+	r := &route{
+		pattern:  strings.ToLower(pattern),
+		priority: priority,
+		handler:  handler,
+	}
+
+	router.mutex.Lock()
+	r.order = router.nextOrder
+	router.nextOrder++
+	router.routes = append(router.routes, r)
+	router.invalidate()
+	router.mutex.Unlock()
+
+	return HandlerHandle{remove: func() { router.remove(r) }}
+}
+
+func (router *router) remove(r *route) {
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+
+	for i := range router.routes {
+		if router.routes[i] == r {
+			router.routes = append(router.routes[:i:i], router.routes[i+1:]...)
+			router.invalidate()
+			return
+		}
+	}
+}
+
+// list gets all routes in the order they were added.
+func (router *router) list() []*route {
+	router.mutex.RLock()
+	defer router.mutex.RUnlock()
+
+	return router.routes
+}
+
+func (router *router) dispatch(event *Event, client *Client) {
+	name := strings.ToLower(event.name)
+
+	router.mutex.RLock()
+	routes, ok := router.cache[name]
+	router.mutex.RUnlock()
+
+	if !ok {
+		routes = router.match(name)
+	}
+
+	for _, r := range routes {
+		r.handler(event, client)
+	}
+}
+
+// match finds, sorts and caches the routes for the event name.
+func (router *router) match(name string) []*route {
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+
+	if routes, ok := router.cache[name]; ok {
+		return routes
+	}
+
+	kindPattern := name + ".*"
+	if dot := strings.IndexByte(name, '.'); dot != -1 {
+		kindPattern = name[:dot] + ".*"
+	}
+
+	routes := make([]*route, 0, 8)
+	for _, r := range router.routes {
+		if r.pattern == "*" || r.pattern == name || r.pattern == kindPattern {
+			routes = append(routes, r)
+		}
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].priority != routes[j].priority {
+			return routes[i].priority > routes[j].priority
+		}
+
+		return routes[i].order < routes[j].order
+	})
+
+	if len(router.cache) >= routerCacheSize {
+		router.invalidate()
+	}
+	router.cache[name] = routes
+
+	return routes
+}
+
+// invalidate clears the cache. The router mutex must be held.
+func (router *router) invalidate() {
+	for key := range router.cache {
+		delete(router.cache, key)
+	}
+}
@@ -0,0 +1,65 @@
+package irc_test
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/gissleh/irc"
+)
+
+func TestClient_On(t *testing.T) {
+	client := irc.New(context.Background(), irc.Config{})
+
+	calls := make([]string, 0, 16)
+	record := func(name string) irc.Handler {
+		return func(event *irc.Event, client *irc.Client) {
+			if event.Kind() == "test" || event.Kind() == "other" {
+				calls = append(calls, name+":"+event.Name())
+			}
+		}
+	}
+
+	client.On("test.foo", record("exact"))
+	client.On("test.*", record("kind"))
+	allHandle := client.AddHandler(record("all"))
+	client.OnPriority("TEST.Foo", 10, record("first"))
+	client.OnPriority("*", -10, record("last"))
+	fooHandle := client.On("test.foo", record("exact2"))
+
+	emit := func(kind, verb string) {
+		if err := client.EmitSync(context.Background(), irc.NewEvent(kind, verb)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	emit("test", "foo")
+	emit("test", "bar")
+	emit("other", "foo")
+
+	fooHandle.Remove()
+	fooHandle.Remove()
+	allHandle.Remove()
+
+	emit("test", "foo")
+
+	expected := []string{
+		"first:test.foo", "exact:test.foo", "kind:test.foo", "all:test.foo", "exact2:test.foo", "last:test.foo",
+		"kind:test.bar", "all:test.bar", "last:test.bar",
+		"all:other.foo", "last:other.foo",
+		"first:test.foo", "exact:test.foo", "kind:test.foo", "last:test.foo",
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Wrong calls:\n%#+v\n%#+v", calls, expected)
+	}
+	// Handlers should still be found after enough event names to clear the cache.
+	for i := 0; i < 300; i++ {
+		emit("spam", strconv.Itoa(i))
+	}
+	calls = calls[:0]
+	emit("test", "bar")
+	if !reflect.DeepEqual(calls, []string{"kind:test.bar", "last:test.bar"}) {
+		t.Errorf("Wrong calls after clearing the cache: %#+v", calls)
+	}
+}
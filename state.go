@@ -1,6 +1,8 @@
 package irc
 
 import (
+	"context"
+
 	"github.com/gissleh/irc/isupport"
 	"github.com/gissleh/irc/list"
 )
@@ -11,6 +13,9 @@ type ClientState struct {
 	Nick      string              `json:"nick"`
 	User      string              `json:"user"`
 	Host      string              `json:"host"`
+	Modes     string              `json:"modes,omitempty"`
+	Account   string              `json:"account,omitempty"`
+	Away      bool                `json:"away,omitempty"`
 	Connected bool                `json:"connected"`
 	Ready     bool                `json:"ready"`
 	Quit      bool                `json:"quit"`
@@ -24,5 +29,67 @@ type ClientStateTarget struct {
 	ID    string      `json:"id"`
 	Kind  string      `json:"kind"`
 	Name  string      `json:"name"`
+	Key   string      `json:"key,omitempty"`
 	Users []list.User `json:"users,omitempty"`
 }
+
+// NewFromState creates a client from a snapshot made by Client.State, such as one saved before
+// a restart. The client ID, nick and targets are restored with their IDs, and the channels are
+// rejoined with their keys once the client is connected and registered. They count as parted
+// until then. Any caps that were
+// enabled will be requested again if the server offers them, even the ones this package does
+// not request by default.
+//
+// The user lists are not restored, since they will be replaced upon rejoining anyway.
+func NewFromState(ctx context.Context, config Config, state ClientState) *Client {
+	client := newClient(ctx, config)
+
+	if state.ID != "" {
+		client.id = state.ID
+	}
+	client.nick = state.Nick
+	client.user = state.User
+	client.host = state.Host
+
+	for _, cap := range state.Caps {
+		if !client.capWanted(cap) {
+			client.extraCaps = append(client.extraCaps, cap)
+		}
+	}
+
+	targets := make([]Target, 0, len(state.Targets))
+	for _, tstate := range state.Targets {
+		id := tstate.ID
+		if id == "" {
+			id = generateClientID("T")
+		}
+
+		switch tstate.Kind {
+		case "status":
+			client.status.id = id
+		case "channel":
+			targets = append(targets, &Channel{
+				id:       id,
+				name:     tstate.Name,
+				key:      tstate.Key,
+				parted:   true,
+				userlist: list.New(&client.isupport),
+			})
+		case "query":
+			user := list.User{Nick: tstate.Name}
+			if len(tstate.Users) > 0 {
+				user = tstate.Users[0]
+			}
+
+			targets = append(targets, &Query{id: id, user: user})
+		}
+	}
+
+	client.start()
+
+	for _, target := range targets {
+		_ = client.AddTarget(target)
+	}
+
+	return client
+}
@@ -0,0 +1,99 @@
+package irc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/internal/irctest"
+)
+
+func TestNewFromState(t *testing.T) {
+	state := irc.ClientState{
+		ID:   "C0123456789abcdef",
+		Nick: "Restored",
+		Caps: []string{"draft/chathistory", "server-time"},
+		Targets: []irc.ClientStateTarget{
+			{ID: "T0000000000000001", Kind: "status", Name: "Status"},
+			{ID: "T0000000000000002", Kind: "channel", Name: "#Open"},
+			{ID: "T0000000000000003", Kind: "channel", Name: "#Keyed", Key: "secret"},
+			{ID: "T0000000000000004", Kind: "query", Name: "Friend"},
+		},
+	}
+
+	client := irc.NewFromState(context.Background(), irc.Config{
+		Nick:     "Test",
+		User:     "Tester",
+		RealName: "...",
+		SendRate: 1000,
+	}, state)
+
+	checkTargets := func() error {
+		if client.ID() != state.ID {
+			return errors.New("client ID should be restored")
+		}
+		if client.Status().ID() != "T0000000000000001" {
+			return errors.New("status ID should be restored")
+		}
+		if channel := client.Channel("#open"); channel == nil || channel.ID() != "T0000000000000002" {
+			return errors.New("#Open should be restored")
+		}
+		if channel := client.Channel("#keyed"); channel == nil || channel.ID() != "T0000000000000003" {
+			return errors.New("#Keyed should be restored")
+		}
+		if query := client.Query("Friend"); query == nil || query.ID() != "T0000000000000004" {
+			return errors.New("query with Friend should be restored")
+		}
+
+		return nil
+	}
+	if err := checkTargets(); err != nil {
+		t.Fatal(err)
+	}
+	if !client.Channel("#Open").Parted() {
+		t.Fatal("restored channels should be parted until rejoined")
+	}
+
+	interaction := irctest.Interaction{
+		Strict: true,
+		Lines: []irctest.InteractionLine{
+			{Client: "CAP LS 302"},
+			{Client: "NICK Restored"},
+			{Client: "USER Tester 8 * :..."},
+			{Server: ":testserver.example.com CAP * LS :server-time draft/chathistory"},
+			{Client: "CAP REQ :server-time draft/chathistory"},
+			{Server: ":testserver.example.com CAP * ACK :server-time draft/chathistory"},
+			{Client: "CAP END"},
+			{Server: ":testserver.example.com 001 Restored :Welcome to the Test IRC Network Restored!~Tester@127.0.0.1"},
+			{Client: "WHO Restored"},
+			{Server: ":testserver.example.com 376 Restored :End of /MOTD command."},
+			{Client: "JOIN #Keyed,#Open secret"},
+			{Server: ":Restored!~Tester@127.0.0.1 JOIN #Open"},
+			{Server: ":Restored!~Tester@127.0.0.1 JOIN #Keyed"},
+			{Server: ":Gisle!~irce@10.32.0.1 JOIN #Keyed"},
+			{Server: "PING :testserver.example.com"},
+			{Client: "PONG :testserver.example.com"},
+			{Callback: func() error {
+				if err := checkTargets(); err != nil {
+					return err
+				}
+				if !client.CapEnabled("draft/chathistory") {
+					return errors.New("draft/chathistory should be enabled")
+				}
+				if _, ok := client.Channel("#Keyed").UserList().User("Gisle"); !ok {
+					return errors.New("Gisle should be in the rejoined channel")
+				}
+				if client.State().Targets[2].Key != "secret" {
+					return errors.New("key should be in the state")
+				}
+				if client.Channel("#Keyed").Parted() {
+					return errors.New("rejoined channel should not be parted")
+				}
+
+				return nil
+			}},
+		},
+	}
+	runInteraction(t, client, &interaction)
+}
@@ -0,0 +1,135 @@
+package irc
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// An EventStore is a persistent log of events, partitioned by network and target.
+type EventStore interface {
+	// Append stores the event in the log of each of its targets. Events without targets are
+	// not stored.
+	Append(network string, event *Event) error
+
+	// Query gets the events for the target name that happened within the time range, including
+	// from but excluding to. They are returned in the order they were appended, with their
+	// targets unresolved.
+	Query(network, targetName string, from, to time.Time) ([]*Event, error)
+}
+
+// EventStoreHandler makes a handler that appends events to the store under the network name. Events
+// of the `input`, `hook` and `client` kinds are not stored, nor are replayed events. Any errors will be
+// emitted as `error.store` events, which are not stored either.
+func EventStoreHandler(store EventStore, network string) Handler {
+	return func(event *Event, client *Client) {
+		switch event.kind {
+		case "input", "hook", "client":
+			return
+		case "error":
+			if event.verb == "store" {
+				return
+			}
+		}
+		if event.replayed || len(event.targets) == 0 {
+			return
+		}
+
+		if err := store.Append(network, event); err != nil {
+			client.EmitNonBlocking(NewErrorEvent("store", "Failed to store event: "+err.Error(), "store_failed", err))
+		}
+	}
+}
+
+// Replay feeds stored events through the client's handlers without affecting the client's state. The
+// targets are resolved by ID first, then by kind and name, so they will refer to the client's current
+// targets where possible. Unresolved targets are left out. See Emit for what the returned context is
+// for, which in this case belongs to the last event. The events are emitted with Emit to keep them in
+// order, so Replay must not be called from a handler as it will deadlock if the event channel fills up.
+func (client *Client) Replay(events []*Event) context.Context {
+	ctx, cancel := context.WithCancel(client.ctx)
+	cancel()
+
+	for _, event := range events {
+		replay := event.Copy()
+		replay.replayed = true
+		replay.targets = client.resolveTargetRefs(event.targetRefs)
+
+		ctx = client.Emit(*replay)
+	}
+
+	return ctx
+}
+
+func (client *Client) resolveTargetRefs(refs []eventTargetRef) []Target {
+	targets := make([]Target, 0, len(refs))
+
+	for _, ref := range refs {
+		var target Target
+		if ref.ID != "" {
+			target = client.TargetByID(ref.ID)
+		}
+		if target == nil && ref.Kind != "" {
+			if ref.Kind == "status" {
+				target = client.status
+			} else {
+				target = client.Target(ref.Kind, ref.Name)
+			}
+		}
+
+		if target != nil {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets
+}
+
+// storedEvent is the JSON representation of an event in a store, with the target it was stored for.
+type storedEvent struct {
+	eventJSONData
+
+	Hidden     bool   `json:"hidden,omitempty"`
+	TargetKind string `json:"targetKind"`
+	TargetName string `json:"targetName"`
+}
+
+func newStoredEvent(event *Event, target Target) storedEvent {
+	return storedEvent{
+		eventJSONData: eventJSONData{
+			Version:    eventJSONVersion,
+			Name:       event.name,
+			Kind:       event.kind,
+			Verb:       event.verb,
+			Time:       event.Time,
+			Nick:       event.Nick,
+			User:       event.User,
+			Host:       event.Host,
+			Args:       event.Args,
+			Text:       event.Text,
+			Tags:       event.Tags,
+			RenderTags: event.RenderTags,
+			Targets:    []string{target.ID()},
+		},
+		Hidden:     event.hidden,
+		TargetKind: target.Kind(),
+		TargetName: target.Name(),
+	}
+}
+
+func (stored *storedEvent) event() *Event {
+	event := eventFromJSONData(stored.eventJSONData)
+	event.hidden = stored.Hidden
+
+	if len(event.targetRefs) == 0 {
+		event.targetRefs = append(event.targetRefs, eventTargetRef{})
+	}
+	event.targetRefs[0].Kind = stored.TargetKind
+	event.targetRefs[0].Name = stored.TargetName
+
+	return &event
+}
+
+func storeTargetKey(name string) string {
+	return strings.ToLower(name)
+}
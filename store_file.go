@@ -0,0 +1,122 @@
+package irc
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A FileEventStore is an EventStore that appends events as JSON lines to files in a directory. It
+// is partitioned into one file per network, target and day (in UTC), which makes the path of a
+// file `<dir>/<network>/<target>/<yyyy-mm-dd>.jsonl`.
+type FileEventStore struct {
+	mutex sync.Mutex
+	dir   string
+}
+
+// NewFileEventStore creates a file event store in the directory. The directory is created
+// upon the first append if it does not exist.
+func NewFileEventStore(dir string) *FileEventStore {
+	return &FileEventStore{dir: dir}
+}
+
+// Append appends the event to the file of each of its targets.
+func (store *FileEventStore) Append(network string, event *Event) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for _, target := range event.targets {
+		data, err := json.Marshal(newStoredEvent(event, target))
+		if err != nil {
+			return err
+		}
+
+		path := store.path(network, target.Name(), event.Time)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+
+		_, err = file.Write(append(data, '\n'))
+		closeErr := file.Close()
+		if err != nil {
+			return err
+		}
+		if closeErr != nil {
+			return closeErr
+		}
+	}
+
+	return nil
+}
+
+// Query reads the events within the time range from the target's files.
+func (store *FileEventStore) Query(network, targetName string, from, to time.Time) ([]*Event, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	events := make([]*Event, 0, 64)
+
+	day := from.UTC().Truncate(time.Hour * 24)
+	for day.Before(to) {
+		file, err := os.Open(store.path(network, targetName, day))
+		day = day.Add(time.Hour * 24)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		reader := bufio.NewReader(file)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 1 {
+				stored := storedEvent{}
+				if jsonErr := json.Unmarshal(line, &stored); jsonErr == nil && !stored.Time.Before(from) && stored.Time.Before(to) {
+					events = append(events, stored.event())
+				}
+			}
+
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				_ = file.Close()
+				return nil, err
+			}
+		}
+
+		_ = file.Close()
+	}
+
+	return events, nil
+}
+
+func (store *FileEventStore) path(network, targetName string, t time.Time) string {
+	return filepath.Join(
+		store.dir,
+		storePathSegment(network),
+		storePathSegment(storeTargetKey(targetName)),
+		t.UTC().Format("2006-01-02")+".jsonl",
+	)
+}
+
+// storePathSegment escapes a network or target name for use as a directory name. The names `.`
+// and `..` are left alone by url.PathEscape, so their dots are escaped too to keep them inside
+// the store's directory.
+func storePathSegment(name string) string {
+	if name == "." || name == ".." {
+		return strings.Replace(name, ".", "%2E", -1)
+	}
+
+	return url.PathEscape(name)
+}
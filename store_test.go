@@ -0,0 +1,136 @@
+package irc_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gissleh/irc"
+	"github.com/gissleh/irc/internal/irctest"
+)
+
+func TestFileEventStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "irc-store-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := irc.NewFileEventStore(dir)
+
+	client := irc.New(context.Background(), irc.Config{
+		Nick:          "Test",
+		User:          "Tester",
+		RealName:      "...",
+		SendRate:      1000,
+		UseServerTime: true,
+	})
+
+	logger := irctest.EventLog{}
+	client.AddHandler(irc.EventStoreHandler(store, "Example/Net"))
+	client.AddHandler(logger.Handler)
+
+	interaction := irctest.Interaction{
+		Lines: []irctest.InteractionLine{
+			{Client: "CAP LS 302"},
+			{Client: "NICK Test"},
+			{Client: "USER Tester 8 * :..."},
+			{Server: ":testserver.example.com CAP * LS :server-time"},
+			{Client: "CAP REQ :server-time"},
+			{Server: ":testserver.example.com CAP * ACK :server-time"},
+			{Client: "CAP END"},
+			{Server: ":testserver.example.com 001 Test :Welcome to the Test IRC Network Test!~Tester@127.0.0.1"},
+			{Server: ":Test!~Tester@127.0.0.1 JOIN #Test"},
+			{Server: "@time=2020-01-01T23:58:00.000Z :Gisle!~irce@10.32.0.1 JOIN #Test"},
+			{Server: "@time=2020-01-01T23:59:00.000Z :Gisle!~irce@10.32.0.1 PRIVMSG #Test :Before midnight"},
+			{Server: "@time=2020-01-02T00:01:00.000Z :Gisle!~irce@10.32.0.1 PRIVMSG #Test :After midnight"},
+			{Server: "@time=2020-01-02T00:02:00.000Z :Gisle!~irce@10.32.0.1 PRIVMSG #test :Too late"},
+			{Server: "@time=2020-01-02T00:03:00.000Z :Gisle!~irce@10.32.0.1 PART #Test"},
+			{Server: "PING :testserver.example.com"},
+			{Client: "PONG :testserver.example.com"},
+		},
+	}
+	runInteraction(t, client, &interaction)
+
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 1, 2, 0, 2, 0, 0, time.UTC)
+	events, err := store.Query("Example/Net", "#TEST", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	texts := make([]string, 0, len(events))
+	for _, event := range events {
+		if event.Name() == "packet.privmsg" {
+			texts = append(texts, event.Text)
+		}
+	}
+	if len(texts) != 2 || texts[0] != "Before midnight" || texts[1] != "After midnight" {
+		t.Fatalf("Unexpected messages: %#+v", texts)
+	}
+
+	channel := client.Channel("#Test")
+	if channel == nil {
+		t.Fatal("Channel #Test should exist")
+	}
+
+	<-client.Replay(events).Done()
+
+	replayed := logger.Last("packet", "PRIVMSG")
+	if replayed == nil || !replayed.Replayed() {
+		t.Fatal("Replayed event should be logged as replayed")
+	}
+	if replayed.Text != "After midnight" || replayed.ChannelTarget() != channel {
+		t.Errorf("Replayed event should be in #Test: %#+v", replayed)
+	}
+	if !replayed.Time.Equal(time.Date(2020, 1, 2, 0, 1, 0, 0, time.UTC)) {
+		t.Errorf("Replayed event should keep its time, got %s", replayed.Time)
+	}
+	if logger.Last("packet", "JOIN") == nil || !logger.Last("packet", "JOIN").Replayed() {
+		t.Error("Gisle's join should be replayed")
+	}
+	if _, ok := channel.UserList().User("Gisle"); ok {
+		t.Error("Replaying should not change the channel's user list")
+	}
+}
+
+func TestFileEventStore_DotNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "irc-store-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := irc.NewFileEventStore(filepath.Join(dir, "store"))
+	client := irc.New(context.Background(), irc.Config{Nick: "Test"})
+
+	event := irc.NewEvent("packet", "privmsg")
+	event.Time = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	event.Text = "Hello"
+	event.AddTarget(client.Status())
+
+	for _, network := range []string{".", ".."} {
+		if err := store.Append(network, &event); err != nil {
+			t.Fatal(err)
+		}
+
+		events, err := store.Query(network, client.Status().Name(), event.Time, event.Time.Add(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || events[0].Text != "Hello" {
+			t.Errorf("Event should be stored for %q, got %d events", network, len(events))
+		}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "store" {
+		t.Error("Nothing should be written outside of the store's directory")
+	}
+}